@@ -1,6 +1,7 @@
 package util
 
 import (
+	"fmt"
 	"github.com/mavolin/repogen/internal/pkgutil"
 	"go/types"
 	"golang.org/x/tools/go/packages"
@@ -112,6 +113,56 @@ func isPrimitive(s string) bool {
 	return primitiveRegexp.MatchString(s)
 }
 
+// VersionField returns the name of the field of the entity obj with the
+// underlying struct s that is used for optimistic concurrency, or an empty
+// string if there is none.
+//
+// A field is used for optimistic concurrency if it is tagged with version,
+// or, if obj is a crud entity, if it is named Version and of an integer type.
+// It is an error if a field tagged with version is not of an integer type.
+func VersionField(pkg *packages.Package, obj types.Object, s *types.Struct) (string, error) {
+	var named string
+
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+
+		if _, ok := ParseStructTag(s.Tag(i))["version"]; ok {
+			if !isInteger(f.Type()) {
+				return "", fmt.Errorf("version field %s must be of an integer type", f.Name())
+			}
+			return f.Name(), nil
+		}
+
+		if f.Name() == "Version" && isInteger(f.Type()) {
+			named = f.Name()
+		}
+	}
+
+	if named == "" || len(pkgutil.FindDirectives(pkg, obj, "crud")) == 0 {
+		return "", nil
+	}
+
+	return named, nil
+}
+
+func isInteger(t types.Type) bool {
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsInteger != 0
+}
+
+// TenantField returns the name of the field the entity obj is scoped to a
+// tenant by, as set by a repogen:crud:tenant directive, or an empty string if
+// obj is not scoped to a tenant.
+func TenantField(pkg *packages.Package, obj types.Object) string {
+	for _, dir := range pkgutil.FindDirectives(pkg, obj, "crud") {
+		if dir.Directive == "tenant" {
+			return dir.Args
+		}
+	}
+
+	return ""
+}
+
 type StructTag map[string]string
 
 func ParseStructTag(tag string) StructTag {
@@ -66,7 +66,8 @@ func run() error {
 
 func loadPackage() (*packages.Package, error) {
 	load, err := packages.Load(&packages.Config{
-		Mode: packages.NeedName | packages.NeedTypes | packages.NeedDeps | packages.NeedCompiledGoFiles | packages.NeedSyntax,
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedDeps | packages.NeedCompiledGoFiles | packages.NeedSyntax |
+			packages.NeedModule,
 	}, ".")
 	if err != nil {
 		return nil, err
@@ -4,13 +4,18 @@ import (
 	"embed"
 	"errors"
 	"fmt"
+	"github.com/iancoleman/strcase"
 	"github.com/mavolin/repogen/internal/goimports"
 	"github.com/mavolin/repogen/internal/pkgutil"
 	"github.com/mavolin/repogen/internal/util"
+	"github.com/mavolin/repogen/module/search"
 	"go/types"
 	"golang.org/x/tools/go/packages"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"text/template"
 )
@@ -41,15 +46,24 @@ type (
 		NoUnwrap, NoWrap bool
 
 		Fields []Field
+		// Version is the field used for optimistic concurrency, if any.
+		Version *Field
+		// Tenant is the field the entity is scoped to a tenant by, if any.
+		Tenant *Field
+
+		Search *Search
 	}
 	Field struct {
 		GetterName string
 		GetterType Type
+		// PK indicates whether the field is a pk.
+		PK         bool
 		SetterName string
 		SetterType SetterType
 
 		ModelsName string
 		ModelsType Type
+		Column     string
 
 		RelName string
 		RelType Type
@@ -67,6 +81,40 @@ type (
 		IsNullable bool
 		IsArray    bool
 	}
+	Search struct {
+		Type       string
+		FilterType string
+
+		Fields       []SearchField
+		FilterFields []SearchField
+
+		// DeletedColumn is the column that must be null, unless
+		// IncludeDeleted is set.
+		DeletedColumn string
+		// ExcludeDeleted and OnlyDeleted are the qualified names of the
+		// DeletedFilter constants, if the search uses a DeletedFilter
+		// instead of IncludeDeleted.
+		ExcludeDeleted, OnlyDeleted string
+
+		// Paginate is the kind of pagination used by the search, i.e.
+		// "offset", "cursor" or empty if the search is not paginated.
+		Paginate string
+		// SortFields are the fields the results can be sorted by.
+		SortFields []SortField
+		// PKs are the pk fields, by which the results are ordered last.
+		PKs []Field
+	}
+	SearchField struct {
+		Name   string
+		Func   string
+		Column string
+	}
+	SortField struct {
+		// Field is the qualified name of the sort field constant.
+		Field string
+		// Getter is the getter field sorted by.
+		Getter Field
+	}
 	SetterType struct {
 		Type       string
 		Unptr      string
@@ -270,6 +318,33 @@ func findEntities(pkg *packages.Package, mdir ModelsDirective) ([]Entity, error)
 			return nil, err
 		}
 
+		if version, _ := util.VersionField(pkg, getterObj, getter); version != "" {
+			for j, f := range e.Fields {
+				if f.GetterName == version && f.ModelsName != "" {
+					e.Version = &e.Fields[j]
+				}
+			}
+		}
+
+		if tenant := util.TenantField(pkg, getterObj); tenant != "" {
+			for j, f := range e.Fields {
+				if f.GetterName == tenant && f.ModelsName != "" {
+					e.Tenant = &e.Fields[j]
+				}
+			}
+
+			if e.Tenant == nil {
+				return nil, objErr(pkg, getterObj, fmt.Sprintf("%s: found no column for the tenant", tenant))
+			} else if e.Tenant.ModelsType.IsNullable {
+				return nil, objErr(pkg, getterObj, fmt.Sprintf("%s: tenant column must not be nullable", tenant))
+			}
+		}
+
+		e.Search, err = findSearch(pkg, mdir, getterObj, e.Fields)
+		if err != nil {
+			return nil, err
+		}
+
 		es = append(es, e)
 	}
 
@@ -281,6 +356,11 @@ func findFields(
 	getter, setter, model, relations *types.Struct,
 ) ([]Field, error) {
 	fields := make([]Field, 0, getter.NumFields())
+	tenant := util.TenantField(pkg, getterObj)
+	version, err := util.VersionField(pkg, getterObj, getter)
+	if err != nil {
+		return nil, objErr(pkg, getterObj, err.Error())
+	}
 
 	for i := 0; i < getter.NumFields(); i++ {
 		getterf := getter.Field(i)
@@ -290,8 +370,10 @@ func findFields(
 			continue
 		}
 
+		_, pk := tag["pk"]
 		f := Field{
 			GetterName: getterf.Name(),
+			PK:         pk,
 		}
 
 		getterTyp := resolveType(mdir.Pkg, getterf.Type())
@@ -318,6 +400,10 @@ func findFields(
 		default:
 			f.SetterName = set
 		}
+		if getterf.Name() == version || getterf.Name() == tenant {
+			f.SetterName = ""
+			f.NoUnwrap = true
+		}
 		unwrap := tag["unwrap"]
 		switch unwrap {
 		case "-":
@@ -366,6 +452,7 @@ func findFields(
 
 			modelsType := resolveType(mdir.Pkg, modelsf.Type())
 			f.ModelsType = *modelsType
+			f.Column = strconv.Quote(columnName(model, f.ModelsName))
 		}
 
 		if rel := tag["rel"]; rel != "" {
@@ -404,6 +491,130 @@ func findFields(
 	return fields, nil
 }
 
+func columnName(model *types.Struct, name string) string {
+	for i := 0; i < model.NumFields(); i++ {
+		if model.Field(i).Name() != name {
+			continue
+		}
+
+		col, _, _ := strings.Cut(reflect.StructTag(model.Tag(i)).Get("db"), ",")
+		if col != "" {
+			return col
+		}
+		break
+	}
+
+	return strcase.ToSnake(name)
+}
+
+func findSearch(pkg *packages.Package, mdir ModelsDirective, getterObj types.Object, fields []Field) (*Search, error) {
+	se, err := search.FindEntity(pkg, getterObj)
+	if err != nil || se == nil {
+		return nil, err
+	}
+
+	searchObj := pkg.Types.Scope().Lookup(se.SearchType)
+	if searchObj == nil {
+		return nil, objErr(pkg, getterObj, fmt.Sprintf("found no search type named %q", se.SearchType))
+	}
+	s := Search{Type: pkgutil.NameInPackage(mdir.Pkg, searchObj.Type()), Paginate: se.Paginate}
+
+	if se.FilterType != "" {
+		filterObj := pkg.Types.Scope().Lookup(se.FilterType)
+		if filterObj == nil {
+			return nil, objErr(pkg, getterObj, fmt.Sprintf("found no filter type named %q", se.FilterType))
+		}
+		s.FilterType = pkgutil.NameInPackage(mdir.Pkg, filterObj.Type())
+	}
+
+	columns := make(map[string]string, len(fields))
+	for _, f := range fields {
+		if f.Column != "" {
+			columns[f.GetterName] = f.Column
+		}
+	}
+
+	for _, f := range fields {
+		if f.PK && f.Column != "" {
+			s.PKs = append(s.PKs, f)
+		}
+	}
+	if s.Paginate == "cursor" && len(s.PKs) == 0 {
+		return nil, objErr(pkg, getterObj, "found no pk column, which is required for cursor pagination")
+	}
+
+	// the constants are generated into the package of the search type
+	qual := strings.TrimSuffix(s.Type, searchObj.Name())
+	for _, sf := range se.SortFields {
+		i := slices.IndexFunc(fields, func(f Field) bool { return f.GetterName == sf.Name })
+		if i < 0 || fields[i].Column == "" {
+			return nil, objErr(pkg, getterObj, fmt.Sprintf("%s: cannot sort by field without column", sf.Name))
+		}
+
+		s.SortFields = append(s.SortFields, SortField{Field: qual + se.SortFieldType + sf.Name, Getter: fields[i]})
+	}
+
+	for _, sf := range se.Fields {
+		switch {
+		case sf.Name == "IncludeDeleted" || (sf.Name == "Deleted" && sf.Type == "DeletedFilter"):
+			if sf.Name == "Deleted" {
+				s.ExcludeDeleted = qual + "ExcludeDeleted"
+				s.OnlyDeleted = qual + "OnlyDeleted"
+			}
+
+			s.DeletedColumn = columns["DeletedAt"]
+			if s.DeletedColumn == "" {
+				s.DeletedColumn = columns["DeletedBy"]
+			}
+			if s.DeletedColumn == "" {
+				return nil, objErr(pkg, getterObj, "found no column for DeletedAt or DeletedBy")
+			}
+		case sf.SQLFunc() != "":
+			col, err := searchColumn(pkg, getterObj, sf, columns)
+			if err != nil {
+				return nil, err
+			}
+
+			s.Fields = append(s.Fields, SearchField{Name: sf.Name, Func: sf.SQLFunc(), Column: col})
+		}
+	}
+
+	for _, sf := range se.FilterFields {
+		col, err := searchColumn(pkg, getterObj, sf, columns)
+		if err != nil {
+			return nil, err
+		}
+
+		s.FilterFields = append(s.FilterFields, SearchField{Name: sf.Name, Func: sf.SQLFunc(), Column: col})
+	}
+
+	return &s, nil
+}
+
+// searchColumn returns the column expression for sf.
+// For fulltext fields, this is a []string of all searched columns.
+func searchColumn(
+	pkg *packages.Package, getterObj types.Object, sf search.Field, columns map[string]string,
+) (string, error) {
+	if sf.FieldNames == nil {
+		col := columns[sf.FieldName]
+		if col == "" {
+			return "", objErr(pkg, getterObj, fmt.Sprintf("%s: cannot search field without column", sf.FieldName))
+		}
+		return col, nil
+	}
+
+	cols := make([]string, len(sf.FieldNames))
+	for i, name := range sf.FieldNames {
+		cols[i] = columns[name]
+		if cols[i] == "" {
+			return "", objErr(pkg, getterObj, fmt.Sprintf("%s: cannot search field without column", name))
+		}
+	}
+
+	return "[]string{" + strings.Join(cols, ", ") + "}", nil
+}
+
 func resolveType(pkg *packages.Package, typ types.Type) *Type {
 	var t Type
 
@@ -0,0 +1,116 @@
+package crud
+
+import (
+	"github.com/mavolin/repogen/internal/goimports"
+	"os"
+	"text/template"
+)
+
+const cacheOutName = "crud_cache.repogen.go"
+
+var cacheTpl = template.Must(template.ParseFS(templates, "cache.gotpl"))
+
+type (
+	CacheData struct {
+		Package  string
+		Entities []Entity
+		Tx       bool
+		// Methods are the methods of the Repository and of the tenant-bound
+		// repositories that read or write entities.
+		Methods []CacheMethod
+		// Tenants are the entities scoped to a tenant.
+		Tenants []Entity
+	}
+
+	CacheMethod struct {
+		DecoratorMethod
+
+		// Receiver is the type implementing the method.
+		Receiver string
+		// Repo is the expression of the wrapped repository.
+		Repo string
+		// Cached is the expression of the CachedRepository providing the
+		// cache and its options.
+		Cached string
+		// Tenant is the expression of the tenant added to cache keys, if
+		// any.
+		Tenant string
+	}
+)
+
+// generateCache generates a caching decorator for the Repository.
+func generateCache(pkgName string, es []Entity, base []string, tx bool) error {
+	f, err := parseOut()
+	if err != nil {
+		return err
+	}
+
+	data := CacheData{Package: pkgName, Entities: es, Tx: tx}
+
+	// tenants maps the repositories of the tenant-scoped entities to their
+	// entities, whose methods are implemented by the tenant-bound
+	// repositories.
+	tenants := make(map[string]*Entity)
+	for i, e := range es {
+		if e.TenantField != "" {
+			tenants[e.Repository] = &es[i]
+			data.Tenants = append(data.Tenants, e)
+		}
+	}
+
+	for _, d := range findDecorators(f, es, base, "Cached") {
+		e := tenants[d.Interface]
+		if d.Interface != "Repository" && e == nil {
+			continue
+		}
+
+		for _, m := range d.Methods {
+			switch m.Op {
+			case "", "Exists", "Count":
+				continue
+			}
+
+			cm := CacheMethod{DecoratorMethod: m, Receiver: "CachedRepository", Repo: "r.Repository", Cached: "r"}
+			if e != nil {
+				cm = CacheMethod{
+					DecoratorMethod: m,
+					Receiver:        "cached" + e.Singular + "TenantRepository",
+					Repo:            "r." + e.Repository,
+					Cached:          "r.cached",
+					Tenant:          "r.tenantID",
+				}
+			}
+
+			data.Methods = append(data.Methods, cm)
+		}
+	}
+
+	out, err := os.Create(cacheOutName)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := cacheTpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = cacheTpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
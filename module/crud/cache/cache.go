@@ -0,0 +1,333 @@
+package cache
+
+import (
+	"container/list"
+	"encoding"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is a cache used by a generated CachedRepository.
+//
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored under key, if any, and not expired.
+	Get(key string) (v any, ok bool)
+	// Set stores v under key.
+	// If ttl is greater than 0, v expires after ttl.
+	Set(key string, v any, ttl time.Duration)
+	// Delete removes the value stored under key, if any.
+	Delete(key string)
+}
+
+// Options configures the caching of an entity.
+type Options struct {
+	// TTL is the duration after which cached entities and search results
+	// expire.
+	// If TTL is 0, they only expire when they are invalidated, or evicted by
+	// the cache.
+	TTL time.Duration
+	// Search indicates whether search results are cached as well.
+	Search bool
+}
+
+// Key returns the cache key for the values vs, using the given namespace,
+// prefix and generation.
+//
+// Equal values, e.g. equal search structs, result in equal keys.
+// Optional values, such as omit.Val and omitnull.Val, are encoded along with
+// their state, so that unset, null and zero values result in different keys.
+func Key(namespace, prefix string, gen uint64, vs ...any) string {
+	var b strings.Builder
+	b.WriteString(strconv.Quote(namespace))
+	b.WriteByte(':')
+	b.WriteString(prefix)
+	b.WriteByte(':')
+	b.WriteString(strconv.FormatUint(gen, 10))
+
+	for _, v := range vs {
+		b.WriteByte(':')
+		writeKey(&b, reflect.ValueOf(v))
+	}
+
+	return b.String()
+}
+
+type (
+	setter interface{ IsSet() bool }
+	nuller interface{ IsNull() bool }
+)
+
+func writeKey(b *strings.Builder, v reflect.Value) {
+	if !v.IsValid() {
+		b.WriteString("nil")
+		return
+	}
+
+	if v.CanInterface() {
+		if o, ok := v.Interface().(nuller); ok && o.IsNull() {
+			b.WriteString("null")
+			return
+		}
+		if o, ok := v.Interface().(setter); ok && !o.IsSet() {
+			b.WriteString("unset")
+			return
+		}
+		if get := v.MethodByName("GetOrZero"); get.IsValid() && get.Type().NumIn() == 0 && get.Type().NumOut() == 1 {
+			b.WriteString("set(")
+			writeKey(b, get.Call(nil)[0])
+			b.WriteByte(')')
+			return
+		}
+
+		if m, ok := v.Interface().(encoding.TextMarshaler); ok && (v.Kind() != reflect.Pointer || !v.IsNil()) {
+			if text, err := m.MarshalText(); err == nil {
+				b.WriteString(strconv.Quote(string(text)))
+				return
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		b.WriteByte('&')
+		writeKey(b, v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		b.WriteString(v.Elem().Type().String())
+		writeKey(b, v.Elem())
+	case reflect.Struct:
+		b.WriteByte('{')
+		for i := 0; i < v.NumField(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeKey(b, v.Field(i))
+		}
+		b.WriteByte('}')
+	case reflect.Slice:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+		fallthrough
+	case reflect.Array:
+		b.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeKey(b, v.Index(i))
+		}
+		b.WriteByte(']')
+	case reflect.Map:
+		if v.IsNil() {
+			b.WriteString("nil")
+			return
+		}
+
+		entries := make([]string, 0, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			var e strings.Builder
+			writeKey(&e, it.Key())
+			e.WriteByte('=')
+			writeKey(&e, it.Value())
+			entries = append(entries, e.String())
+		}
+		slices.Sort(entries)
+
+		b.WriteString("map[" + strings.Join(entries, ",") + "]")
+	case reflect.String:
+		b.WriteString(strconv.Quote(v.String()))
+	case reflect.Bool:
+		b.WriteString(strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		b.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		b.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	default:
+		b.WriteString(fmt.Sprintf("%v", v))
+	}
+}
+
+// Clone returns a deep copy of v, so that cached values can't be modified
+// through the values returned by a CachedRepository, and vice versa.
+//
+// Unexported fields are copied shallowly.
+func Clone[T any](v T) T {
+	c := reflect.New(reflect.TypeOf(&v).Elem()).Elem()
+	c.Set(clone(reflect.ValueOf(&v).Elem(), make(map[any]reflect.Value)))
+	return c.Interface().(T)
+}
+
+// clone returns a deep copy of v.
+// seen maps the pointers already cloned to their clones, so that cyclic
+// values are cloned correctly.
+func clone(v reflect.Value, seen map[any]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+
+		if c, ok := seen[v.Interface()]; ok {
+			return c
+		}
+
+		c := reflect.New(v.Type().Elem())
+		seen[v.Interface()] = c
+		c.Elem().Set(clone(v.Elem(), seen))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.New(v.Type()).Elem()
+		c.Set(clone(v.Elem(), seen))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if c.Field(i).CanSet() {
+				c.Field(i).Set(clone(v.Field(i), seen))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(clone(v.Index(i), seen))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(clone(v.Index(i), seen))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(it.Key(), clone(it.Value(), seen))
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// LRU is an in-memory Cache that evicts the least recently used values, once
+// it holds more than a fixed number of values.
+type LRU struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// order orders the entries from most to least recently used.
+	order *list.List
+}
+
+var _ Cache = (*LRU)(nil)
+
+type lruEntry struct {
+	key     string
+	v       any
+	expires time.Time
+}
+
+// DefaultLRUSize is the number of values held by an LRU, if no positive size
+// is given.
+const DefaultLRUSize = 10_000
+
+// NewLRU returns a new LRU that holds up to size values.
+//
+// If size is not positive, DefaultLRUSize is used instead.
+func NewLRU(size int) *LRU {
+	if size <= 0 {
+		size = DefaultLRUSize
+	}
+
+	return &LRU{
+		size:    size,
+		entries: make(map[string]*list.Element, size),
+		order:   list.New(),
+	}
+}
+
+func (c *LRU) Get(key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	e := elem.Value.(*lruEntry)
+	if !e.expires.IsZero() && time.Now().After(e.expires) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return e.v, true
+}
+
+func (c *LRU) Set(key string, v any, ttl time.Duration) {
+	e := &lruEntry{key: key, v: v}
+	if ttl > 0 {
+		e.expires = time.Now().Add(ttl)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = e
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+func (c *LRU) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+}
+
+func (c *LRU) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*lruEntry).key)
+}
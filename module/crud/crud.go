@@ -7,6 +7,8 @@ import (
 	"github.com/mavolin/repogen/internal/goimports"
 	"github.com/mavolin/repogen/internal/pkgutil"
 	"github.com/mavolin/repogen/internal/util"
+	"github.com/mavolin/repogen/module/search"
+	"go/ast"
 	"go/types"
 	"golang.org/x/tools/go/packages"
 	"os"
@@ -28,21 +30,85 @@ type (
 		Entities []Entity
 		Extra    []string
 		Base     []string
+		Tx       bool
+		// ContextActor is the type of the actor obtained from the context
+		// using ActorFrom, or empty if actors are passed as parameters.
+		ContextActor string
+		Errors       Errors
+	}
+
+	// Errors are the names of the generated errors shared by all entities.
+	Errors struct {
+		// NotFound and Conflict are the sentinel errors wrapped by all
+		// errors returned if an entity does not exist or conflicts with an
+		// existing one.
+		NotFound, Conflict string
+		// NotFoundType is the error type returned if the entity with the
+		// given pks does not exist.
+		NotFoundType string
 	}
 
 	Entity struct {
 		Repository                                  string
 		Singular, Plural                            string
 		Create, Get, Search, Edit, Delete           bool
+		Count, Exists, Upsert, CreateMany, EditMany bool
+		Restore, Purge                              bool
 		CreatedByType, UpdatedByType, DeletedByType string
 		Extra                                       []string
 		SearchType                                  string
+		Paginate, PageType                          string
+		VersionType, VersionField                   string
+		SoftDelete, DeletedFilter                   bool
+		// HTTPPath is the path the entity's http handlers are served under,
+		// or empty if no handlers are generated.
+		HTTPPath string
+		// ContextActor indicates whether the actor is obtained from the
+		// context using ActorFrom, instead of being passed as createdBy,
+		// updatedBy and deletedBy.
+		ContextActor bool
+		// TenantField is the name of the field the entity is scoped to a
+		// tenant by, or empty if the entity is not tenant-scoped.
+		TenantField, TenantType string
+		// Key is the struct the entity's pks are combined into, or nil if
+		// each pk is passed as a separate param.
+		//
+		// If set, PKs consists of a single param named key of the key's
+		// type.
+		Key *Key
+		// Rels are the names of the entity's fields tagged rel, which can
+		// be loaded along with the entity using its Load type.
+		Rels []string
+		// ErrNotFound and ErrConflict are the names of the entity's sentinel
+		// errors, and NewNotFoundError that of the function creating the
+		// error returned if the entity does not exist.
+		ErrNotFound, ErrConflict, NewNotFoundError string
 
 		PKs []Param
 	}
 	Param struct {
 		Name string
 		Type string
+		// Field is the name of the entity's field, if this is a pk.
+		Field string
+	}
+
+	// Key is a struct combining the pks of an entity.
+	Key struct {
+		Type string
+		// Fields are the entity's pks, in the order they are declared.
+		Fields []KeyField
+	}
+	KeyField struct {
+		Param
+		// Parser is the function parsing the field from a string.
+		Parser string
+		// Compare is the format of the expression comparing two values of
+		// the field, taking the two values as operands.
+		Compare string
+		// Text indicates whether the field is formatted using its
+		// MarshalText method.
+		Text bool
 	}
 )
 
@@ -54,6 +120,16 @@ func Generate(pkg *packages.Package, packagePath string) error {
 
 	if len(es) == 0 {
 		_ = os.Remove(outName)
+		_ = os.Remove(mockOutName)
+		_ = os.Remove(memoryOutName)
+		removeSuite(pkg.Name)
+		_ = os.Remove(instrumentOutName)
+		_ = os.Remove(cacheOutName)
+		_ = os.Remove(httpOutName)
+		_ = os.Remove(openAPIOutName)
+		_ = os.Remove(protoOutName)
+		_ = os.Remove(protoConvOutName)
+		_ = os.Remove(eventsOutName)
 		return nil
 	}
 
@@ -67,6 +143,36 @@ func Generate(pkg *packages.Package, packagePath string) error {
 		return err
 	}
 
+	errs, err := findErrors(pkg, packagePath, es)
+	if err != nil {
+		return err
+	}
+
+	contextActor, _ := findDirective(pkg, packagePath, "actor")
+	if contextActor != "" {
+		for i, e := range es {
+			for _, typ := range []string{e.CreatedByType, e.UpdatedByType, e.DeletedByType} {
+				if typ != "" && typ != contextActor {
+					obj := pkg.Types.Scope().Lookup(e.Singular)
+					return objErr(pkg, obj, fmt.Sprintf("actor type %s differs from the package's actor type %s",
+						typ, contextActor))
+				}
+			}
+
+			es[i].ContextActor = true
+		}
+	}
+
+	tx := hasDirective(pkg, packagePath, "tx")
+	mock := hasDirective(pkg, packagePath, "mock")
+	memory := hasDirective(pkg, packagePath, "memory")
+	suite := hasDirective(pkg, packagePath, "suite")
+	instrument := hasDirective(pkg, packagePath, "instrument")
+	cache := hasDirective(pkg, packagePath, "cache")
+	openAPITitle, openAPI := findDirective(pkg, packagePath, "openapi")
+	protoArgs, proto := findDirective(pkg, packagePath, "proto")
+	events := hasDirective(pkg, packagePath, "events")
+
 	out, err := os.Create(outName)
 	if err != nil {
 		return wrapErr(err)
@@ -75,10 +181,13 @@ func Generate(pkg *packages.Package, packagePath string) error {
 	in, done, err := goimports.Pipe(out)
 
 	data := Data{
-		Package:  pkg.Name,
-		Entities: es,
-		Extra:    extra,
-		Base:     base,
+		Package:      pkg.Name,
+		Entities:     es,
+		Extra:        extra,
+		Base:         base,
+		Tx:           tx,
+		ContextActor: contextActor,
+		Errors:       errs,
 	}
 
 	if err := tpl.Execute(in, data); err != nil {
@@ -98,7 +207,157 @@ func Generate(pkg *packages.Package, packagePath string) error {
 		return wrapErr(err)
 	}
 
-	return nil
+	if !mock {
+		_ = os.Remove(mockOutName)
+	} else if err := generateMock(pkg.Name); err != nil {
+		return err
+	}
+
+	if !memory {
+		_ = os.Remove(memoryOutName)
+	} else if err := generateMemory(pkg, es, extra, base, tx); err != nil {
+		return err
+	}
+
+	if !instrument {
+		_ = os.Remove(instrumentOutName)
+	} else if err := generateInstrument(pkg.Name, es, base, errs); err != nil {
+		return err
+	}
+
+	if !cache {
+		_ = os.Remove(cacheOutName)
+	} else if err := generateCache(pkg.Name, es, base, tx); err != nil {
+		return err
+	}
+
+	if !events {
+		_ = os.Remove(eventsOutName)
+	} else if err := generateEvents(pkg.Name, es, base, tx); err != nil {
+		return err
+	}
+
+	if err := generateHTTP(pkg, es, errs); err != nil {
+		return err
+	}
+
+	if !openAPI {
+		_ = os.Remove(openAPIOutName)
+	} else if err := generateOpenAPI(pkg, es, openAPITitle); err != nil {
+		return err
+	}
+
+	if !proto {
+		_ = os.Remove(protoOutName)
+		_ = os.Remove(protoConvOutName)
+	} else if err := generateProto(pkg, es, protoArgs); err != nil {
+		return err
+	}
+
+	if !suite {
+		removeSuite(pkg.Name)
+		return nil
+	}
+
+	removeOldSuite()
+	return generateSuite(pkg, es, errs)
+}
+
+// ActorType returns the type of the actor passed as createdBy, updatedBy and
+// deletedBy, or an empty string if e has none of these or obtains the actor
+// from the context.
+func (e Entity) ActorType() string {
+	if e.ContextActor {
+		return ""
+	}
+
+	for _, typ := range []string{e.CreatedByType, e.UpdatedByType, e.DeletedByType} {
+		if typ != "" {
+			return typ
+		}
+	}
+
+	return ""
+}
+
+// Actor returns the argument to pass for a createdBy, updatedBy or deletedBy
+// param of type typ, prefixed by a comma, or an empty string if typ is empty
+// or e obtains the actor from the context.
+//
+// Params of the ActorType are passed the variable actor.
+func (e Entity) Actor(typ string) string {
+	switch {
+	case typ == "" || e.ContextActor:
+		return ""
+	case typ == e.ActorType():
+		return ", actor"
+	default:
+		return ", *new(" + typ + ")"
+	}
+}
+
+// ActorParam returns the declaration of the createdBy, updatedBy or deletedBy
+// param with the given name and type, prefixed by a comma, or an empty string
+// if typ is empty or e obtains the actor from the context.
+func (e Entity) ActorParam(name, typ string) string {
+	if typ == "" || e.ContextActor {
+		return ""
+	}
+
+	return ", " + name + " " + typ
+}
+
+// KeyText reports whether any of the entities' keys has a field formatted
+// using its MarshalText method.
+func (d Data) KeyText() bool {
+	for _, e := range d.Entities {
+		if e.Key == nil {
+			continue
+		}
+
+		for _, f := range e.Key.Fields {
+			if f.Text {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// KeyFields returns the entity's pks, regardless of whether they are
+// combined into a Key.
+func (e Entity) KeyFields() []Param {
+	if e.Key == nil {
+		return e.PKs
+	}
+
+	fields := make([]Param, len(e.Key.Fields))
+	for i, f := range e.Key.Fields {
+		fields[i] = f.Param
+	}
+
+	return fields
+}
+
+// KeyValue returns the expression accessing the value of the pk p, as
+// returned by KeyFields, from the pk params.
+func (e Entity) KeyValue(p Param) string {
+	if e.Key == nil {
+		return p.Name
+	}
+
+	return "key." + p.Field
+}
+
+// PKArgs returns the pk params, prefixed by a comma.
+func (e Entity) PKArgs() string {
+	var s string
+	for _, pk := range e.PKs {
+		s += ", " + pk.Name
+	}
+
+	return s
 }
 
 func findExtra(pkg *packages.Package, packagePath string) ([]string, error) {
@@ -143,6 +402,100 @@ func findBase(pkg *packages.Package, packagePath string) ([]string, error) {
 	return base, nil
 }
 
+// hasDirective reports whether the package has the //repogen:repo:<directive>
+// directive.
+func hasDirective(pkg *packages.Package, packagePath string, directive string) bool {
+	_, ok := findDirective(pkg, packagePath, directive)
+	return ok
+}
+
+// findDirective returns the args of the package's //repogen:repo:<directive>
+// directive, and whether the package has it.
+func findDirective(pkg *packages.Package, packagePath string, directive string) (string, bool) {
+	for i, path := range pkg.CompiledGoFiles {
+		if filepath.Dir(path) != packagePath {
+			continue
+		}
+
+		file := pkg.Syntax[i]
+		for _, cg := range file.Comments {
+			for _, dir := range pkgutil.ParseDirectives(cg) {
+				if dir.Module == "repo" && dir.Directive == directive {
+					return dir.Args, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// findErrors returns the names of the errors shared by all entities, and
+// sets the names of the entities' errors.
+//
+// By default, these are ErrNotFound, ErrConflict and NotFoundError, and
+// Err<Entity>NotFound, Err<Entity>Conflict and New<Entity>NotFoundError.
+// If the package has the //repogen:repo:errors <name> directive, they are
+// Err<name>NotFound, Err<name>Conflict and <name>NotFoundError, and
+// Err<name><Entity>NotFound, Err<name><Entity>Conflict and
+// New<name><Entity>NotFoundError instead, so that they don't clash with the
+// package's own declarations.
+func findErrors(pkg *packages.Package, packagePath string, es []Entity) (Errors, error) {
+	name, _ := findDirective(pkg, packagePath, "errors")
+	errs := Errors{
+		NotFound:     "Err" + name + "NotFound",
+		Conflict:     "Err" + name + "Conflict",
+		NotFoundType: name + "NotFoundError",
+	}
+
+	generated := map[string]bool{errs.NotFound: true, errs.Conflict: true, errs.NotFoundType: true}
+	for i, e := range es {
+		es[i].ErrNotFound = "Err" + name + e.Singular + "NotFound"
+		es[i].ErrConflict = "Err" + name + e.Singular + "Conflict"
+		es[i].NewNotFoundError = "New" + name + e.Singular + "NotFoundError"
+		generated[es[i].ErrNotFound] = true
+		generated[es[i].ErrConflict] = true
+		generated[es[i].NewNotFoundError] = true
+	}
+
+	// look at the syntax, as the declarations of the previously generated
+	// files take precedence in the package's scope
+	for i, path := range pkg.CompiledGoFiles {
+		if strings.HasSuffix(path, ".repogen.go") {
+			continue
+		}
+
+		for _, decl := range pkg.Syntax[i].Decls {
+			var names []*ast.Ident
+			switch decl := decl.(type) {
+			case *ast.FuncDecl:
+				if decl.Recv == nil {
+					names = []*ast.Ident{decl.Name}
+				}
+			case *ast.GenDecl:
+				for _, spec := range decl.Specs {
+					switch spec := spec.(type) {
+					case *ast.ValueSpec:
+						names = append(names, spec.Names...)
+					case *ast.TypeSpec:
+						names = append(names, spec.Name)
+					}
+				}
+			}
+
+			for _, name := range names {
+				if generated[name.Name] {
+					return Errors{}, pkgutil.PosError(pkg, name.Pos(), fmt.Errorf(
+						"crud: %s: clashes with the generated error declaration of the same name, "+
+							"use //repogen:repo:errors <name> to rename the generated errors", name.Name))
+				}
+			}
+		}
+	}
+
+	return errs, nil
+}
+
 func findEntities(pkg *packages.Package) ([]Entity, error) {
 	scope := pkg.Types.Scope()
 	es := make([]Entity, 0, len(scope.Names()))
@@ -170,6 +523,7 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 			Edit:       true,
 			Delete:     true,
 			SearchType: obj.Name() + "SearchData",
+			PageType:   obj.Name() + "Page",
 		}
 
 		sdirs := pkgutil.FindDirectives(pkg, obj, "search")
@@ -182,7 +536,23 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 				break
 			}
 		}
+		for _, sdir := range sdirs {
+			switch sdir.Directive {
+			case "paginate":
+				switch sdir.Args {
+				case "offset", "cursor":
+					e.Paginate = sdir.Args
+				default:
+					return nil, objErr(pkg, obj, fmt.Sprintf("unknown pagination mode %q", sdir.Args))
+				}
+			case "deleted":
+				e.DeletedFilter = true
+			}
+		}
 
+		e.SoftDelete = pkgutil.LookupField(s, "DeletedAt") != nil || pkgutil.LookupField(s, "DeletedBy") != nil
+
+		var keyType string
 		for _, dir := range dirs {
 			switch dir.Directive {
 			case "":
@@ -195,6 +565,27 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 				if err := parseOps(pkg, obj, &e, dir.Args); err != nil {
 					return nil, err
 				}
+			case "http":
+				e.HTTPPath = strings.TrimSuffix(dir.Args, "/")
+				if e.HTTPPath == "" {
+					e.HTTPPath = "/" + strcase.ToKebab(e.Plural)
+				}
+			case "key":
+				keyType = dir.Args
+				if keyType == "" {
+					keyType = obj.Name() + "Key"
+				}
+			case "tenant":
+				f := pkgutil.LookupField(s, dir.Args)
+				if f == nil {
+					return nil, objErr(pkg, obj, fmt.Sprintf("tenant: no field named %q", dir.Args))
+				}
+
+				e.TenantField = dir.Args
+				e.TenantType = pkgutil.NameInPackage(pkg, f.Type())
+				if e.TenantType == "" {
+					return nil, objErr(pkg, obj, "tenant must be a named type")
+				}
 			default:
 				return nil, objErr(pkg, obj, fmt.Sprintf("unrecognized directive %q", dir.Directive))
 			}
@@ -209,6 +600,17 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 			return nil, objErr(pkg, obj, "need at least one pk")
 		}
 
+		if keyType != "" {
+			e.Key, err = newKey(pkg, obj, s, keyType, e.PKs)
+			if err != nil {
+				return nil, err
+			}
+
+			e.PKs = []Param{{Name: "key", Type: keyType}}
+		}
+
+		e.Rels = findRels(s)
+
 		e.CreatedByType, err = findUpdatedByType(pkg, obj, s, "CreatedBy")
 		if err != nil {
 			return nil, err
@@ -217,7 +619,12 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 		if err != nil {
 			return nil, err
 		}
-		e.DeletedByType, err = findUpdatedByType(pkg, obj, s, "CreatedBy")
+		e.DeletedByType, err = findUpdatedByType(pkg, obj, s, "DeletedBy")
+		if err != nil {
+			return nil, err
+		}
+
+		e.VersionField, e.VersionType, err = findVersion(pkg, obj, s)
 		if err != nil {
 			return nil, err
 		}
@@ -247,6 +654,26 @@ func parseOps(pkg *packages.Package, obj types.Object, e *Entity, args string) e
 			e.Edit = true
 		case "delete":
 			e.Delete = true
+		case "count":
+			e.Count = true
+		case "exists":
+			e.Exists = true
+		case "upsert":
+			e.Upsert = true
+		case "createmany":
+			e.CreateMany = true
+		case "editmany":
+			e.EditMany = true
+		case "restore", "purge":
+			if !e.SoftDelete {
+				return objErr(pkg, obj, fmt.Sprintf("%s requires a DeletedAt or DeletedBy field", op))
+			}
+
+			if op == "restore" {
+				e.Restore = true
+			} else {
+				e.Purge = true
+			}
 		default:
 			return objErr(pkg, obj, fmt.Sprintf("unknown crud operation %q", op))
 		}
@@ -266,8 +693,9 @@ func findPKs(pkg *packages.Package, obj types.Object, s *types.Struct) ([]Param,
 		}
 
 		pk := Param{
-			Name: strcase.ToLowerCamel(f.Name()),
-			Type: pkgutil.NameInPackage(pkg, f.Type()),
+			Name:  strcase.ToLowerCamel(f.Name()),
+			Type:  pkgutil.NameInPackage(pkg, f.Type()),
+			Field: f.Name(),
 		}
 		if pk.Type == "" {
 			return nil, objErr(pkg, obj, "pk must be a named type")
@@ -279,6 +707,64 @@ func findPKs(pkg *packages.Package, obj types.Object, s *types.Struct) ([]Param,
 	return pks, nil
 }
 
+func newKey(pkg *packages.Package, obj types.Object, s *types.Struct, typ string, pks []Param) (*Key, error) {
+	key := &Key{Type: typ, Fields: make([]KeyField, len(pks))}
+
+	for i, pk := range pks {
+		t := pkgutil.LookupField(s, pk.Field).Type()
+
+		f := KeyField{Param: pk, Parser: search.QueryParser(pkg, t)}
+		if f.Parser == "" {
+			return nil, objErr(pkg, obj, fmt.Sprintf("key: %s: cannot parse pk, use a parseid type", pk.Field))
+		}
+
+		if m, _, _ := types.LookupFieldOrMethod(t, true, nil, "MarshalText"); m != nil {
+			_, f.Text = m.(*types.Func)
+		}
+
+		if basic, ok := t.Underlying().(*types.Basic); ok && basic.Info()&types.IsOrdered != 0 {
+			f.Compare = "cmp.Compare(%s, %s)"
+		} else if hasCompare(t) {
+			f.Compare = "%s.Compare(%s)"
+		} else {
+			f.Compare = "strings.Compare(fmt.Sprint(%s), fmt.Sprint(%s))"
+		}
+
+		key.Fields[i] = f
+	}
+
+	return key, nil
+}
+
+// hasCompare reports whether t has a method Compare(t) int.
+func hasCompare(t types.Type) bool {
+	m, _, _ := types.LookupFieldOrMethod(t, true, nil, "Compare")
+	f, ok := m.(*types.Func)
+	if !ok {
+		return false
+	}
+
+	sig := f.Type().(*types.Signature)
+	if sig.Params().Len() != 1 || sig.Results().Len() != 1 || !types.Identical(sig.Params().At(0).Type(), t) {
+		return false
+	}
+
+	basic, ok := sig.Results().At(0).Type().(*types.Basic)
+	return ok && basic.Kind() == types.Int
+}
+
+func findRels(s *types.Struct) []string {
+	var rels []string
+
+	for i := 0; i < s.NumFields(); i++ {
+		if util.ParseStructTag(s.Tag(i))["rel"] != "" {
+			rels = append(rels, s.Field(i).Name())
+		}
+	}
+
+	return rels
+}
+
 func findUpdatedByType(pkg *packages.Package, obj types.Object, s *types.Struct, name string) (string, error) {
 	for i := 0; i < s.NumFields(); i++ {
 		f := s.Field(i)
@@ -297,6 +783,21 @@ func findUpdatedByType(pkg *packages.Package, obj types.Object, s *types.Struct,
 	return "", nil
 }
 
+func findVersion(pkg *packages.Package, obj types.Object, s *types.Struct) (field, typ string, err error) {
+	field, err = util.VersionField(pkg, obj, s)
+	if err != nil {
+		return "", "", objErr(pkg, obj, err.Error())
+	} else if field == "" {
+		return "", "", nil
+	}
+
+	typ = pkgutil.NameInPackage(pkg, s.Field(fieldIndex(s, field)).Type())
+	if typ == "" {
+		return "", "", objErr(pkg, obj, "version must be a named type")
+	}
+	return field, typ, nil
+}
+
 func wrapErr(err error) error {
 	if err == nil {
 		return nil
@@ -0,0 +1,358 @@
+package crud
+
+import (
+	"github.com/iancoleman/strcase"
+	"github.com/mavolin/repogen/internal/goimports"
+	"os"
+	"text/template"
+)
+
+const eventsOutName = "crud_events.repogen.go"
+
+var eventsTpl = template.Must(template.ParseFS(templates, "events.gotpl"))
+
+type (
+	EventsData struct {
+		Package string
+		Tx      bool
+		Events  []Event
+		Methods []EventMethod
+		// Tenants are the entities scoped to a tenant.
+		Tenants []Entity
+	}
+
+	// Event is an event type published for an operation on an entity.
+	Event struct {
+		Name string
+		// Doc are the lines of the doc comment of the event, excluding the
+		// event's name.
+		Doc    []string
+		Fields []EventField
+	}
+	EventField struct {
+		Name string
+		Type string
+		// Doc is the doc comment of the field, excluding the field's name.
+		Doc string
+	}
+
+	// EventMethod is a method of the Repository that publishes an event.
+	EventMethod struct {
+		DecoratorMethod
+
+		// Receiver is the type implementing the method.
+		Receiver string
+		// RepoType is the type of the repository the operation is executed
+		// on.
+		RepoType string
+
+		Event string
+		// Values are the values of the event's fields, as expressions using
+		// the method's params and results.
+		// Fields without value are left zero.
+		Values []EventValue
+		// Many indicates whether the method creates multiple entities, and
+		// publishes one event per element of data, accessible as data[i].
+		Many bool
+		// ContextActor indicates whether the actor must be obtained from the
+		// context, and is stored in the variable actor.
+		ContextActor bool
+	}
+	EventValue struct {
+		Field string
+		Value string
+	}
+)
+
+// generateEvents generates event types for the entities, and a decorator
+// publishing them.
+func generateEvents(pkgName string, es []Entity, base []string, tx bool) error {
+	f, err := parseOut()
+	if err != nil {
+		return err
+	}
+
+	data := EventsData{Package: pkgName, Tx: tx}
+	for _, e := range es {
+		data.Events = append(data.Events, entityEvents(e)...)
+		if e.TenantField != "" {
+			data.Tenants = append(data.Tenants, e)
+		}
+	}
+
+	// tenants maps the repositories of the tenant-scoped entities to their
+	// entities, whose methods are implemented by the tenant-bound
+	// repositories.
+	tenants := make(map[string]*Entity)
+	for i, e := range es {
+		if e.TenantField != "" {
+			tenants[e.Repository] = &es[i]
+		}
+	}
+
+	for _, d := range findDecorators(f, es, base, "Eventing") {
+		e := tenants[d.Interface]
+		if d.Interface != "Repository" && e == nil {
+			continue
+		}
+
+		for _, m := range d.Methods {
+			em, ok := eventMethod(m)
+			if !ok {
+				continue
+			}
+
+			if e != nil {
+				em.Receiver = "eventing" + e.Singular + "TenantRepository"
+				em.RepoType = e.Repository
+				em.Values = append(em.Values, EventValue{Field: e.TenantField, Value: "r.tenantID"})
+			}
+
+			data.Methods = append(data.Methods, em)
+		}
+	}
+
+	out, err := os.Create(eventsOutName)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := eventsTpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = eventsTpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+// entityEvents returns the events published for the operations of e.
+func entityEvents(e Entity) []Event {
+	var pks []EventField
+	for _, pk := range e.PKs {
+		pks = append(pks, EventField{Name: eventPKField(pk), Type: pk.Type})
+	}
+	actor := func(name, typ string) []EventField {
+		if typ == "" {
+			return nil
+		}
+		return []EventField{{Name: name, Type: typ}}
+	}
+	data := EventField{Name: "Data", Type: e.Singular + "Setter"}
+
+	var es []Event
+
+	if e.Create || e.CreateMany {
+		ev := Event{Name: e.Singular + "Created", Doc: []string{"is published when a " + e.Singular + " is created."}}
+		if len(e.PKs) == 1 {
+			ev.Fields = append(ev.Fields, pks...)
+		}
+		ev.Fields = append(ev.Fields, actor("CreatedBy", e.CreatedByType)...)
+		data.Doc = "is the setter the " + e.Singular + " was created with."
+		ev.Fields = append(ev.Fields, data)
+		es = append(es, ev)
+	}
+
+	if e.Upsert {
+		ev := Event{Name: e.Singular + "Upserted", Doc: []string{"is published when a " + e.Singular + " is upserted."}}
+		ev.Fields = append(ev.Fields, pks...)
+		typ := e.CreatedByType
+		if typ == "" {
+			typ = e.UpdatedByType
+		}
+		ev.Fields = append(ev.Fields, actor("UpsertedBy", typ)...)
+		data.Doc = "is the setter the " + e.Singular + " was upserted with."
+		ev.Fields = append(ev.Fields, data)
+		es = append(es, ev)
+	}
+
+	if e.Edit {
+		ev := Event{Name: e.Singular + "Edited", Doc: []string{"is published when a " + e.Singular + " is edited."}}
+		ev.Fields = append(ev.Fields, pks...)
+		if e.VersionType != "" {
+			ev.Fields = append(ev.Fields, EventField{
+				Name: "Version", Type: e.VersionType, Doc: "is the version of the " + e.Singular + " before the edit.",
+			})
+		}
+		ev.Fields = append(ev.Fields, actor("UpdatedBy", e.UpdatedByType)...)
+		data.Doc = "is the setter the " + e.Singular + " was edited with."
+		ev.Fields = append(ev.Fields, data)
+		es = append(es, ev)
+	}
+
+	if e.EditMany {
+		ev := Event{
+			Name: e.Plural + "Edited",
+			Doc: []string{
+				"is published when the " + e.Plural + " matching a search are edited.",
+				"No " + e.Singular + "Edited events are published for the individual " + e.Plural + ".",
+			},
+			Fields: []EventField{{Name: "Search", Type: e.SearchType}},
+		}
+		ev.Fields = append(ev.Fields, actor("UpdatedBy", e.UpdatedByType)...)
+		data.Doc = "is the setter the " + e.Plural + " were edited with."
+		ev.Fields = append(ev.Fields, data, EventField{
+			Name: "Edited", Type: "int", Doc: "is the number of edited " + e.Plural + ".",
+		})
+		es = append(es, ev)
+	}
+
+	if e.Delete || e.Purge {
+		ev := Event{Name: e.Singular + "Deleted", Doc: []string{"is published when a " + e.Singular + " is deleted."}}
+		ev.Fields = append(ev.Fields, pks...)
+		if e.Delete {
+			ev.Fields = append(ev.Fields, actor("DeletedBy", e.DeletedByType)...)
+		}
+		if e.Purge {
+			ev.Fields = append(ev.Fields, EventField{
+				Name: "Purged", Type: "bool", Doc: "indicates whether the " + e.Singular + " was permanently deleted.",
+			})
+		}
+		es = append(es, ev)
+	}
+
+	if e.Restore {
+		ev := Event{
+			Name: e.Singular + "Restored",
+			Doc:  []string{"is published when a soft-deleted " + e.Singular + " is restored."},
+		}
+		ev.Fields = append(ev.Fields, pks...)
+		ev.Fields = append(ev.Fields, actor("RestoredBy", e.UpdatedByType)...)
+		es = append(es, ev)
+	}
+
+	if e.TenantField != "" {
+		tenant := EventField{
+			Name: e.TenantField, Type: e.TenantType,
+			Doc: "is the tenant of the " + e.Singular + ".",
+		}
+		for i := range es {
+			es[i].Fields = append([]EventField{tenant}, es[i].Fields...)
+		}
+	}
+
+	return es
+}
+
+// eventPKField returns the name of the event field holding the pk param p.
+func eventPKField(p Param) string {
+	if p.Field != "" {
+		return p.Field
+	}
+
+	return strcase.ToCamel(p.Name)
+}
+
+// eventMethod returns the EventMethod for m, and whether m publishes an
+// event.
+func eventMethod(m DecoratorMethod) (EventMethod, bool) {
+	if m.Entity == nil {
+		return EventMethod{}, false
+	}
+
+	e := *m.Entity
+	em := EventMethod{
+		DecoratorMethod: m,
+		Receiver:        "EventingRepository",
+		RepoType:        "Repository",
+		ContextActor:    e.ContextActor,
+	}
+
+	pks := func() {
+		for _, pk := range e.PKs {
+			em.Values = append(em.Values, EventValue{Field: eventPKField(pk), Value: pk.Name})
+		}
+	}
+	actor := func(field, param, typ string) {
+		if typ == "" {
+			return
+		}
+
+		if e.ContextActor {
+			param = "actor"
+		}
+		em.Values = append(em.Values, EventValue{Field: field, Value: param})
+	}
+
+	switch m.Op {
+	case "Create", "CreateMany":
+		em.Event = e.Singular + "Created"
+		em.Many = m.Op == "CreateMany"
+		if len(e.PKs) == 1 {
+			pk := e.PKs[0]
+			if em.Many {
+				em.Values = append(em.Values, EventValue{Field: eventPKField(pk), Value: pk.Name + "s[i]"})
+			} else {
+				pks()
+			}
+		}
+		actor("CreatedBy", "createdBy", e.CreatedByType)
+		if em.Many {
+			em.Values = append(em.Values, EventValue{Field: "Data", Value: "data[i]"})
+		} else {
+			em.Values = append(em.Values, EventValue{Field: "Data", Value: "data"})
+		}
+	case "Upsert":
+		em.Event = e.Singular + "Upserted"
+		pks()
+		typ := e.CreatedByType
+		if typ == "" {
+			typ = e.UpdatedByType
+		}
+		actor("UpsertedBy", "upsertedBy", typ)
+		em.Values = append(em.Values, EventValue{Field: "Data", Value: "data"})
+	case "Edit":
+		em.Event = e.Singular + "Edited"
+		pks()
+		if e.VersionType != "" {
+			em.Values = append(em.Values, EventValue{Field: "Version", Value: "version"})
+		}
+		actor("UpdatedBy", "updatedBy", e.UpdatedByType)
+		em.Values = append(em.Values, EventValue{Field: "Data", Value: "data"})
+	case "EditMany":
+		em.Event = e.Plural + "Edited"
+		em.Values = append(em.Values, EventValue{Field: "Search", Value: "search"})
+		actor("UpdatedBy", "updatedBy", e.UpdatedByType)
+		em.Values = append(em.Values,
+			EventValue{Field: "Data", Value: "data"}, EventValue{Field: "Edited", Value: "edited"})
+	case "Delete":
+		em.Event = e.Singular + "Deleted"
+		pks()
+		actor("DeletedBy", "deletedBy", e.DeletedByType)
+	case "Purge":
+		em.Event = e.Singular + "Deleted"
+		pks()
+		em.Values = append(em.Values, EventValue{Field: "Purged", Value: "true"})
+	case "Restore":
+		em.Event = e.Singular + "Restored"
+		pks()
+		actor("RestoredBy", "restoredBy", e.UpdatedByType)
+	default:
+		return em, false
+	}
+
+	for _, v := range em.Values {
+		if v.Value == "actor" {
+			return em, true
+		}
+	}
+
+	em.ContextActor = false
+	return em, true
+}
@@ -0,0 +1,198 @@
+package crud
+
+import (
+	"fmt"
+	"github.com/mavolin/repogen/internal/goimports"
+	"github.com/mavolin/repogen/internal/pkgutil"
+	"github.com/mavolin/repogen/module/search"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const httpOutName = "crud_http.repogen.go"
+
+var httpTpl = template.Must(template.ParseFS(templates, "http.gotpl"))
+
+type (
+	HTTPData struct {
+		Package  string
+		Entities []HTTPEntity
+		Errors   Errors
+	}
+
+	HTTPEntity struct {
+		Entity
+
+		// PKParsers are the functions parsing the pks from the path, in the
+		// order of the pks.
+		PKParsers []string
+		// SearchParseFunc is the function parsing the search struct from a
+		// query, or empty if the entity has no search struct.
+		SearchParseFunc string
+		// VersionParser is the function parsing the version from the
+		// If-Match header.
+		VersionParser string
+		// ActorType is the type of the actor resolved from requests, or
+		// empty if no operation is performed by an actor.
+		// Unlike Entity.ActorType, it is also set if the actor is passed
+		// to the repository through the context.
+		ActorType string
+	}
+)
+
+func generateHTTP(pkg *packages.Package, es []Entity, errs Errors) error {
+	data := HTTPData{Package: pkg.Name, Errors: errs}
+
+	for _, e := range es {
+		if e.HTTPPath == "" {
+			continue
+		}
+
+		he, err := httpEntity(pkg, e)
+		if err != nil {
+			return err
+		}
+
+		data.Entities = append(data.Entities, he)
+	}
+
+	if len(data.Entities) == 0 {
+		_ = os.Remove(httpOutName)
+		return nil
+	}
+
+	// the handlers are registered using the method and wildcard patterns
+	// of http.ServeMux, which are only supported since go1.22
+	if v := moduleGoVersion(pkg); !goVersionAtLeast(v, 22) {
+		return wrapErr(fmt.Errorf("http: handlers require go 1.22 or later, but the go directive of the module "+
+			"is %q; upgrade it using `go mod edit -go=1.22`", v))
+	}
+
+	out, err := os.Create(httpOutName)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := httpTpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = httpTpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+// moduleGoVersion returns the version of the go directive of pkg's module,
+// or an empty string if there is none.
+func moduleGoVersion(pkg *packages.Package) string {
+	if pkg.Module == nil {
+		return ""
+	}
+
+	return pkg.Module.GoVersion
+}
+
+// goVersionAtLeast reports whether the go version v, e.g. "1.22", "1.22.1" or
+// "1.22rc1", is at least 1.minor.
+func goVersionAtLeast(v string, minor int) bool {
+	rest, ok := strings.CutPrefix(v, "1.")
+	if !ok {
+		return false
+	}
+
+	if i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		rest = rest[:i]
+	}
+
+	m, err := strconv.Atoi(rest)
+	return err == nil && m >= minor
+}
+
+func httpEntity(pkg *packages.Package, e Entity) (HTTPEntity, error) {
+	obj := pkg.Types.Scope().Lookup(e.Singular)
+	s := pkgutil.ElemType(obj.Type()).(*types.Struct)
+
+	he := HTTPEntity{Entity: e, PKParsers: make([]string, len(e.PKs))}
+
+	paramActor := e
+	paramActor.ContextActor = false
+	he.ActorType = paramActor.ActorType()
+
+	for i, pk := range e.PKs {
+		if e.Key != nil {
+			he.PKParsers[i] = "Parse" + e.Key.Type
+			continue
+		}
+
+		he.PKParsers[i] = search.QueryParser(pkg, s.Field(fieldIndex(s, pk.Field)).Type())
+		if he.PKParsers[i] == "" {
+			return he, objErr(pkg, obj, fmt.Sprintf("%s: cannot parse pk from path, use a parseid type", pk.Field))
+		}
+	}
+
+	if e.VersionType != "" {
+		he.VersionParser = search.QueryParser(pkg, s.Field(fieldIndex(s, e.VersionField)).Type())
+		if he.VersionParser == "" {
+			return he, objErr(pkg, obj, fmt.Sprintf("%s: cannot parse version from If-Match header", e.VersionField))
+		}
+	}
+
+	if e.Search {
+		se, err := search.FindEntity(pkg, obj)
+		if err != nil {
+			return he, err
+		}
+
+		if se != nil {
+			he.SearchParseFunc = se.ParseFunc
+		}
+	}
+
+	return he, nil
+}
+
+// PKPath returns the path of a single entity, using the pk names as wildcards.
+func (e HTTPEntity) PKPath() string {
+	path := e.HTTPPath
+	for _, pk := range e.PKs {
+		path += "/{" + pk.Name + "}"
+	}
+
+	return path
+}
+
+// ResolvesActor reports whether a handler resolves the actor before calling
+// an operation whose createdBy, updatedBy or deletedBy is of type typ.
+func (e HTTPEntity) ResolvesActor(typ string) bool {
+	return typ != "" && typ == e.ActorType
+}
+
+// Ctx returns the context passed to an operation whose createdBy, updatedBy
+// or deletedBy is of type typ.
+func (e HTTPEntity) Ctx(typ string) string {
+	if e.ContextActor && e.ResolvesActor(typ) {
+		return "ctx"
+	}
+
+	return "r.Context()"
+}
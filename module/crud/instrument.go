@@ -0,0 +1,230 @@
+package crud
+
+import (
+	"github.com/mavolin/repogen/internal/goimports"
+	"go/ast"
+	"go/types"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const instrumentOutName = "crud_instrument.repogen.go"
+
+var instrumentTpl = template.Must(template.ParseFS(templates, "instrument.gotpl"))
+
+type (
+	InstrumentData struct {
+		Package    string
+		Decorators []Decorator
+		Errors     Errors
+	}
+
+	// Decorator is a type wrapping an interface of the generated crud file.
+	Decorator struct {
+		Name      string
+		Interface string
+		Methods   []DecoratorMethod
+	}
+
+	DecoratorMethod struct {
+		MockMethod
+
+		// Entity is the entity the method belongs to, if any.
+		Entity *Entity
+		// Op is the crud operation the method performs, e.g. Get or
+		// EditMany, or empty if it is an extra or base method.
+		Op string
+		// PKs are the params that are pks of Entity.
+		PKs []Param
+		// HasCtx indicates whether the first param is a context.Context.
+		HasCtx bool
+		// Err is the name of the error result, if any.
+		Err string
+		// Wrap is the name of the decorator to wrap the first result in, if
+		// it is an interface of the generated crud file.
+		Wrap string
+		// InTx indicates whether this is the InTx method, whose repository
+		// passed to fn must be wrapped.
+		InTx bool
+	}
+)
+
+// generateInstrument generates instrumenting decorators for all interfaces in
+// the generated crud file.
+func generateInstrument(pkgName string, es []Entity, base []string, errs Errors) error {
+	f, err := parseOut()
+	if err != nil {
+		return err
+	}
+
+	data := InstrumentData{Package: pkgName, Decorators: findDecorators(f, es, base, "Instrumented"), Errors: errs}
+
+	out, err := os.Create(instrumentOutName)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := instrumentTpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = instrumentTpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+// findDecorators returns a decorator named prefix+<interface> for each
+// interface in f.
+//
+// Unlike mocks, decorators implement the methods of embedded interfaces
+// themselves, so that all methods are decorated.
+func findDecorators(f *ast.File, es []Entity, base []string, prefix string) []Decorator {
+	names, ifaces := findInterfaces(f)
+
+	baseNames := make(map[string]bool, len(base))
+	for _, b := range base {
+		name, _, _ := strings.Cut(b, "(")
+		baseNames[strings.TrimSpace(name)] = true
+	}
+
+	// map each method of an entity's repository to its entity
+	entities := make(map[string]*Entity)
+	for i, e := range es {
+		iface, ok := ifaces[e.Repository]
+		if !ok {
+			continue
+		}
+
+		for _, field := range iface.Methods.List {
+			for _, ident := range field.Names {
+				if !baseNames[ident.Name] {
+					entities[ident.Name] = &es[i]
+				}
+			}
+		}
+	}
+
+	ds := make([]Decorator, 0, len(names))
+	for _, name := range names {
+		d := Decorator{Name: prefix + name, Interface: name}
+
+		seen := make(map[string]bool)
+		for _, m := range flattenInterface(ifaces, name) {
+			if seen[m.Name] {
+				continue
+			}
+			seen[m.Name] = true
+
+			d.Methods = append(d.Methods, decoratorMethod(m, entities[m.Name], ifaces, prefix))
+		}
+
+		ds = append(ds, d)
+	}
+
+	return ds
+}
+
+// flattenInterface returns the methods of the interface with the given name,
+// including those of embedded interfaces declared in the same file.
+func flattenInterface(ifaces map[string]*ast.InterfaceType, name string) []MockMethod {
+	var ms []MockMethod
+
+	for _, field := range ifaces[name].Methods.List {
+		ftyp, ok := field.Type.(*ast.FuncType)
+		if !ok {
+			if embed := types.ExprString(field.Type); ifaces[embed] != nil {
+				ms = append(ms, flattenInterface(ifaces, embed)...)
+			}
+			continue
+		}
+
+		for _, ident := range field.Names {
+			ms = append(ms, mockMethod(ident.Name, ftyp))
+		}
+	}
+
+	return ms
+}
+
+func decoratorMethod(m MockMethod, e *Entity, ifaces map[string]*ast.InterfaceType, prefix string) DecoratorMethod {
+	dm := DecoratorMethod{
+		MockMethod: m,
+		HasCtx:     len(m.Params) > 0 && m.Params[0].Type == "context.Context",
+		InTx:       m.Name == "InTx",
+	}
+
+	if e != nil {
+		dm.Entity = e
+		dm.Op = entityOp(*e, m.Name)
+
+		for _, p := range m.Params {
+			for _, pk := range e.PKs {
+				if p.Name == pk.Name && p.Type == pk.Type {
+					dm.PKs = append(dm.PKs, p)
+				}
+			}
+		}
+	}
+
+	for _, r := range m.Results {
+		if r.Type == "error" {
+			dm.Err = r.Name
+		}
+	}
+
+	if len(m.Results) > 0 && ifaces[m.Results[0].Type] != nil {
+		dm.Wrap = prefix + m.Results[0].Type
+	}
+
+	return dm
+}
+
+// entityOp returns the crud operation performed by e's method with the given
+// name, or an empty string if it is an extra method.
+func entityOp(e Entity, method string) string {
+	switch method {
+	case "Create" + e.Singular:
+		return "Create"
+	case "Create" + e.Plural:
+		return "CreateMany"
+	case "Upsert" + e.Singular:
+		return "Upsert"
+	case e.Singular:
+		return "Get"
+	case e.Plural:
+		return "Search"
+	case e.Singular + "Exists":
+		return "Exists"
+	case "Count" + e.Plural:
+		return "Count"
+	case "Edit" + e.Singular:
+		return "Edit"
+	case "Edit" + e.Plural:
+		return "EditMany"
+	case "Delete" + e.Singular:
+		return "Delete"
+	case "Restore" + e.Singular:
+		return "Restore"
+	case "Purge" + e.Singular:
+		return "Purge"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,143 @@
+package instrument
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Op describes a call of a repository method.
+type Op struct {
+	// Method is the name of the called method, e.g. "CreateFoo".
+	Method string
+	// Repository is the name of the generated interface the method belongs
+	// to, e.g. "FooRepository", or, for extra and base methods, that of the
+	// instrumented interface, e.g. "Repository".
+	Repository string
+	// Entity is the name of the entity the method belongs to, or empty if
+	// it is an extra or base method.
+	Entity string
+	// PKs are the pks passed to the method, if any.
+	PKs []slog.Attr
+	// Expected reports whether err is an error callers are expected to
+	// handle, such as a not found or conflict error, rather than a failure
+	// of the repository.
+	//
+	// It may be nil, in which case all errors are failures.
+	Expected func(err error) bool
+}
+
+// Attrs returns the attributes describing op.
+func (op Op) Attrs() []slog.Attr {
+	attrs := make([]slog.Attr, 0, 2+len(op.PKs))
+	attrs = append(attrs, slog.String("method", op.Method))
+	if op.Entity != "" {
+		attrs = append(attrs, slog.String("entity", op.Entity))
+	}
+
+	return append(attrs, op.PKs...)
+}
+
+// Instrumenter instruments calls of repository methods.
+type Instrumenter interface {
+	// Start is called before the method described by op is called.
+	//
+	// The returned context is passed to the method, and end is called with
+	// the error returned by the method, after it returns.
+	Start(ctx context.Context, op Op) (_ context.Context, end func(err error))
+}
+
+type multi []Instrumenter
+
+// Multi returns an Instrumenter that calls all of is.
+func Multi(is ...Instrumenter) Instrumenter {
+	return multi(is)
+}
+
+func (m multi) Start(ctx context.Context, op Op) (context.Context, func(err error)) {
+	ends := make([]func(error), len(m))
+	for i, instr := range m {
+		ctx, ends[i] = instr.Start(ctx, op)
+	}
+
+	return ctx, func(err error) {
+		for i := len(ends) - 1; i >= 0; i-- {
+			ends[i](err)
+		}
+	}
+}
+
+type logger struct {
+	l *slog.Logger
+}
+
+// Logger returns an Instrumenter that logs every call using l.
+//
+// Successful calls are logged at debug level, calls returning an expected
+// error at info level, and failed ones at error level.
+func Logger(l *slog.Logger) Instrumenter {
+	return logger{l: l}
+}
+
+func (l logger) Start(ctx context.Context, op Op) (context.Context, func(err error)) {
+	start := time.Now()
+
+	return ctx, func(err error) {
+		attrs := append(op.Attrs(), slog.Duration("duration", time.Since(start)))
+		level, msg := slog.LevelDebug, "repository call"
+		if err != nil {
+			attrs = append(attrs, slog.Any("err", err))
+			if op.Expected != nil && op.Expected(err) {
+				level = slog.LevelInfo
+			} else {
+				level, msg = slog.LevelError, "repository call failed"
+			}
+		}
+
+		l.l.LogAttrs(ctx, level, msg, attrs...)
+	}
+}
+
+// TimingFunc is called with the duration of every call.
+type TimingFunc func(ctx context.Context, op Op, d time.Duration, err error)
+
+func (f TimingFunc) Start(ctx context.Context, op Op) (context.Context, func(err error)) {
+	start := time.Now()
+
+	return ctx, func(err error) {
+		f(ctx, op, time.Since(start), err)
+	}
+}
+
+// Tracer starts spans, e.g. by wrapping an OpenTelemetry tracer.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, Span)
+}
+
+type Span interface {
+	// End ends the span.
+	// If err is not nil, the span should be marked as failed.
+	End(err error)
+}
+
+type tracing struct {
+	t Tracer
+}
+
+// Tracing returns an Instrumenter that starts a span for every call.
+//
+// The spans are named <Repository>.<Method>, using the name of the
+// repository interface the method belongs to, e.g. FooRepository.CreateFoo.
+func Tracing(t Tracer) Instrumenter {
+	return tracing{t: t}
+}
+
+func (t tracing) Start(ctx context.Context, op Op) (context.Context, func(err error)) {
+	repo := op.Repository
+	if repo == "" {
+		repo = op.Entity + "Repository"
+	}
+
+	ctx, span := t.t.Start(ctx, repo+"."+op.Method, op.Attrs()...)
+	return ctx, span.End
+}
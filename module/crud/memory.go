@@ -0,0 +1,373 @@
+package crud
+
+import (
+	"fmt"
+	"github.com/iancoleman/strcase"
+	"github.com/mavolin/repogen/internal/goimports"
+	"github.com/mavolin/repogen/internal/pkgutil"
+	"github.com/mavolin/repogen/internal/util"
+	"github.com/mavolin/repogen/module/search"
+	"github.com/mavolin/repogen/module/setter"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const memoryOutName = "crud_memory.repogen.go"
+
+var memoryTpl = template.Must(template.ParseFS(templates, "memory.gotpl"))
+
+type (
+	MemoryData struct {
+		Package  string
+		Entities []MemoryEntity
+		Tx       bool
+		// Extra are the methods that can't be implemented in memory, i.e.
+		// the extra and base methods.
+		Extra []string
+	}
+
+	MemoryEntity struct {
+		Entity
+
+		// Var is the name of the map field storing the entities.
+		Var string
+		// Receiver is the type implementing the entity's repository.
+		Receiver string
+		// TenantBound indicates whether Receiver is bound to a tenant, i.e.
+		// whether it only reads and writes the entities of its tenant.
+		TenantBound bool
+		// PKType is the type of the pk, or an unexported struct containing
+		// all pks.
+		PKType string
+		// KeyType is the type of the map key, i.e. PKType, or, if the entity
+		// is scoped to a tenant, an unexported struct containing the tenant
+		// and the pk, so that pks are unique per tenant.
+		KeyType string
+		// AutoID indicates whether the entity has a single integer pk that
+		// is assigned automatically.
+		AutoID bool
+
+		SetterFields []MemoryField
+
+		CreatedAt, UpdatedAt, DeletedAt *MemoryField
+		CreatedBy, UpdatedBy, DeletedBy *MemoryField
+		Version                         *MemoryField
+
+		// SearchFields are the search fields translated to memutil
+		// predicates.
+		SearchFields  []MemorySearchField
+		FilterType    string
+		FilterFields  []MemorySearchField
+		SortFieldType string
+		SortFields    []search.SortField
+		// DeletedField is the name of the search field filtering deleted
+		// entities, either IncludeDeleted or Deleted.
+		DeletedField string
+	}
+
+	MemoryField struct {
+		// Name is the name of the setter field, if any.
+		Name      string
+		FieldName string
+		Type      string
+		IsPtr     bool
+		// Zero is the zero value of the field's type.
+		Zero string
+	}
+
+	MemorySearchField struct {
+		Name   string
+		Func   string
+		Fields []string
+	}
+)
+
+func generateMemory(pkg *packages.Package, es []Entity, extra, base []string, tx bool) error {
+	data := MemoryData{
+		Package:  pkg.Name,
+		Entities: make([]MemoryEntity, len(es)),
+		Tx:       tx,
+		Extra:    append(append([]string(nil), base...), extra...),
+	}
+
+	for i, e := range es {
+		var err error
+		data.Entities[i], err = memoryEntity(pkg, e)
+		if err != nil {
+			return err
+		}
+
+		if len(base) > 0 {
+			data.Extra = append(data.Extra, e.Extra...)
+		}
+	}
+
+	out, err := os.Create(memoryOutName)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := memoryTpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = memoryTpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+func memoryEntity(pkg *packages.Package, e Entity) (MemoryEntity, error) {
+	obj := pkg.Types.Scope().Lookup(e.Singular)
+	s := pkgutil.ElemType(obj.Type()).(*types.Struct)
+
+	me := MemoryEntity{
+		Entity:   e,
+		Var:      strcase.ToLowerCamel(e.Plural),
+		Receiver: "MemoryRepository",
+		PKType:   e.PKs[0].Type,
+	}
+
+	pks := e.KeyFields()
+	if len(e.PKs) > 1 {
+		me.PKType = strcase.ToLowerCamel(e.Singular) + "Key"
+	} else if len(pks) > 1 {
+		// pks are combined into the entity's Key, which is used as key
+	} else if basic, ok := pkgutil.BaseType(s.Field(fieldIndex(s, pks[0].Field)).Type()).(*types.Basic); ok {
+		me.AutoID = basic.Info()&types.IsInteger != 0
+	}
+
+	me.KeyType = me.PKType
+	if e.TenantField != "" {
+		me.KeyType = strcase.ToLowerCamel(e.Singular) + "TenantKey"
+	}
+
+	setterFields, err := setter.ListFields(pkg, obj, s)
+	if err != nil {
+		return me, err
+	}
+
+	for _, sf := range setterFields {
+		if sf.Converted {
+			return me, objErr(pkg, obj, fmt.Sprintf("%s: memory repository does not support settyp and rel fields",
+				sf.FieldName))
+		}
+
+		f := memoryField(pkg, s, sf.FieldName)
+		f.Name = sf.Name
+		me.SetterFields = append(me.SetterFields, *f)
+	}
+
+	for name, dst := range map[string]**MemoryField{
+		"CreatedAt": &me.CreatedAt, "UpdatedAt": &me.UpdatedAt, "DeletedAt": &me.DeletedAt,
+		"CreatedBy": &me.CreatedBy, "UpdatedBy": &me.UpdatedBy, "DeletedBy": &me.DeletedBy,
+	} {
+		*dst = memoryField(pkg, s, name)
+	}
+
+	version, err := util.VersionField(pkg, obj, s)
+	if err != nil {
+		return me, objErr(pkg, obj, err.Error())
+	}
+	if version != "" {
+		me.Version = memoryField(pkg, s, version)
+	}
+
+	se, err := search.FindEntity(pkg, obj)
+	if err != nil || se == nil {
+		return me, err
+	}
+
+	me.SearchFields = memorySearchFields(se.Fields)
+	if se.FilterType != "" {
+		me.FilterType = se.FilterType
+		me.FilterFields = memorySearchFields(se.FilterFields)
+	}
+
+	if len(se.SortFields) > 0 {
+		me.SortFieldType = se.SortFieldType
+		me.SortFields = se.SortFields
+	}
+
+	for _, f := range se.Fields {
+		if f.Name == "IncludeDeleted" || f.Name == "Deleted" {
+			me.DeletedField = f.Name
+		}
+	}
+
+	return me, nil
+}
+
+func memorySearchFields(fields []search.Field) []MemorySearchField {
+	msfs := make([]MemorySearchField, 0, len(fields))
+
+	for _, f := range fields {
+		fn := f.SQLFunc()
+		if fn == "" {
+			continue
+		}
+
+		msf := MemorySearchField{Name: f.Name, Func: fn, Fields: f.FieldNames}
+		if f.FieldName != "" {
+			msf.Fields = []string{f.FieldName}
+		}
+
+		msfs = append(msfs, msf)
+	}
+
+	return msfs
+}
+
+// memoryField returns the field with the given name, or nil if s has no such
+// field.
+func memoryField(pkg *packages.Package, s *types.Struct, name string) *MemoryField {
+	i := fieldIndex(s, name)
+	if i < 0 {
+		return nil
+	}
+
+	typ := s.Field(i).Type()
+	_, isPtr := typ.(*types.Pointer)
+	return &MemoryField{
+		FieldName: name,
+		Type:      pkgutil.NameInPackage(pkg, typ),
+		IsPtr:     isPtr,
+		Zero:      zeroValue(pkg, typ),
+	}
+}
+
+func fieldIndex(s *types.Struct, name string) int {
+	for i := 0; i < s.NumFields(); i++ {
+		if s.Field(i).Name() == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func zeroValue(pkg *packages.Package, t types.Type) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsString != 0:
+			return `""`
+		case u.Info()&types.IsBoolean != 0:
+			return "false"
+		default:
+			return "0"
+		}
+	case *types.Struct, *types.Array:
+		return pkgutil.NameInPackage(pkg, t) + "{}"
+	default:
+		return "nil"
+	}
+}
+
+// Assign returns the statement assigning the value v to the field of e.
+func (f MemoryField) Assign(v string) string {
+	if f.IsPtr {
+		return "e." + f.FieldName + " = &" + v
+	}
+
+	return "e." + f.FieldName + " = " + v
+}
+
+// AssignPtr returns the statement assigning the pointer p to the field of e.
+func (f MemoryField) AssignPtr(p string) string {
+	if f.IsPtr {
+		return "e." + f.FieldName + " = " + p
+	}
+
+	return "e." + f.FieldName + " = *" + p
+}
+
+// BoundToTenant returns a copy of e, whose Receiver is bound to a tenant.
+func (e MemoryEntity) BoundToTenant() MemoryEntity {
+	e.Receiver = "memory" + e.Singular + "TenantRepository"
+	e.TenantBound = true
+	return e
+}
+
+// KeyVar returns the name of the variable storing the map key of the
+// entity identified by the pk params.
+//
+// It is key, unless the pk param key must be converted to the map key.
+func (e MemoryEntity) KeyVar() string {
+	if e.Key != nil && e.TenantBound {
+		return "tenantKey"
+	}
+
+	return "key"
+}
+
+// ParamKey returns the expression creating a map key from the pk params.
+func (e MemoryEntity) ParamKey() string {
+	var key string
+	if len(e.PKs) == 1 {
+		key = e.PKs[0].Name
+	} else {
+		names := make([]string, len(e.PKs))
+		for i, pk := range e.PKs {
+			names[i] = pk.Name
+		}
+
+		key = e.PKType + "{" + strings.Join(names, ", ") + "}"
+	}
+
+	if e.TenantBound {
+		return e.KeyType + "{r.tenantID, " + key + "}"
+	}
+
+	return key
+}
+
+// StoreKey returns the expression creating the map key of the entity v.
+func (e MemoryEntity) StoreKey(v string) string {
+	if e.TenantField != "" {
+		return e.KeyType + "{" + v + "." + e.TenantField + ", " + e.EntityKey(v) + "}"
+	}
+
+	return e.EntityKey(v)
+}
+
+// EntityKey returns the expression creating a pk from the entity v.
+func (e MemoryEntity) EntityKey(v string) string {
+	if e.Key != nil {
+		fields := make([]string, len(e.Key.Fields))
+		for i, f := range e.Key.Fields {
+			fields[i] = v + "." + f.Field
+		}
+
+		return e.Key.Type + "{" + strings.Join(fields, ", ") + "}"
+	}
+
+	if len(e.PKs) == 1 {
+		return v + "." + e.PKs[0].Field
+	}
+
+	fields := make([]string, len(e.PKs))
+	for i, pk := range e.PKs {
+		fields[i] = v + "." + pk.Field
+	}
+
+	return e.PKType + "{" + strings.Join(fields, ", ") + "}"
+}
@@ -0,0 +1,439 @@
+package memutil
+
+import (
+	"fmt"
+	"github.com/aarondl/opt/omit"
+	"github.com/aarondl/opt/omitnull"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// The predicates mirror those of sqlutil, but report whether a single field
+// value matches, instead of building a where clause.
+// Unset values match all fields.
+
+// Set sets *dst to the value of v, if v is set.
+func Set[T any](dst *T, v omit.Val[T]) {
+	if t, ok := v.Get(); ok {
+		*dst = t
+	}
+}
+
+// SetNull sets *dst to the value of v, or nil if v is null, if v is not unset.
+func SetNull[T any](dst **T, v omitnull.Val[T]) {
+	if v.IsUnset() {
+		return
+	} else if v.IsNull() {
+		*dst = nil
+		return
+	}
+
+	t := v.MustGet()
+	*dst = &t
+}
+
+// IsZero reports whether v is the zero value of its type.
+func IsZero(v any) bool {
+	return v == nil || reflect.ValueOf(v).IsZero()
+}
+
+func Eq[T any](v omit.Val[T], field any) bool {
+	t, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && Compare(f, t) == 0
+}
+
+func EqNull[T any](v omitnull.Val[T], field any) bool {
+	if v.IsUnset() {
+		return true
+	}
+
+	f, ok := deref(field)
+	if v.IsNull() {
+		return !ok
+	}
+
+	return ok && Compare(f, v.MustGet()) == 0
+}
+
+func Ne[T any](v omit.Val[T], field any) bool {
+	t, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && Compare(f, t) != 0
+}
+
+func NeNull[T any](v omitnull.Val[T], field any) bool {
+	if v.IsUnset() {
+		return true
+	}
+
+	f, ok := deref(field)
+	if v.IsNull() {
+		return ok
+	}
+
+	return !ok || Compare(f, v.MustGet()) != 0
+}
+
+func Gte[T any](v omit.Val[T], field any) bool {
+	t, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && Compare(f, t) >= 0
+}
+
+// GteNull is the same as Gte, but ignores null values.
+func GteNull[T any](v omitnull.Val[T], field any) bool {
+	return Gte(omit.FromCond(v.Get()), field)
+}
+
+func Lte[T any](v omit.Val[T], field any) bool {
+	t, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && Compare(f, t) <= 0
+}
+
+// LteNull is the same as Lte, but ignores null values.
+func LteNull[T any](v omitnull.Val[T], field any) bool {
+	return Lte(omit.FromCond(v.Get()), field)
+}
+
+func In[T any, S ~[]T](v omit.Val[S], field any) bool {
+	s, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && contains(s, f)
+}
+
+func NotIn[T any, S ~[]T](v omit.Val[S], field any) bool {
+	s, ok := v.Get()
+	if !ok || len(s) == 0 {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && !contains(s, f)
+}
+
+func Prefix[T ~string](v omit.Val[T], field any) bool {
+	t, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && strings.HasPrefix(toString(f), string(t))
+}
+
+// Contains matches fields that contain v case-insensitively.
+func Contains[T ~string](v omit.Val[T], field any) bool {
+	t, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	return ok && strings.Contains(strings.ToLower(toString(f)), strings.ToLower(string(t)))
+}
+
+func IsNull(v omit.Val[bool], field any) bool {
+	isNull, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	_, ok = deref(field)
+	return isNull != ok
+}
+
+// Overlaps matches slice fields that contain at least one of the values.
+func Overlaps[T any, S ~[]T](v omit.Val[S], field any) bool {
+	s, ok := v.Get()
+	if !ok {
+		return true
+	}
+
+	f, ok := deref(field)
+	if !ok {
+		return false
+	}
+
+	fv := reflect.ValueOf(f)
+	for i := 0; i < fv.Len(); i++ {
+		if contains(s, fv.Index(i).Interface()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ContainsAll matches slice fields that contain all the values.
+func ContainsAll[T any, S ~[]T](v omit.Val[S], field any) bool {
+	s, ok := v.Get()
+	if !ok || len(s) == 0 {
+		return true
+	}
+
+	f, ok := deref(field)
+	if !ok {
+		return false
+	}
+
+	fv := reflect.ValueOf(f)
+	elems := make([]any, fv.Len())
+	for i := range elems {
+		elems[i] = fv.Index(i).Interface()
+	}
+
+	for _, t := range s {
+		if !contains(elems, any(t)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FullText matches if the concatenation of fields contains all words of v
+// case-insensitively.
+//
+// Unlike its sqlutil counterpart, FullText doesn't support the websearch
+// syntax.
+func FullText[T ~string](v omit.Val[T], fields ...any) bool {
+	t, ok := v.Get()
+	if !ok || t == "" {
+		return true
+	}
+
+	text := strings.ToLower(joinFields(fields))
+	for _, word := range strings.Fields(strings.ToLower(string(t))) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// FullTextLike matches if any of fields contains v case-insensitively.
+func FullTextLike[T ~string](v omit.Val[T], fields ...any) bool {
+	t, ok := v.Get()
+	if !ok || t == "" {
+		return true
+	}
+
+	for _, field := range fields {
+		if Contains(omit.From(t), field) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Compare compares a and b, returning -1 if a < b, 0 if a == b, and 1 if
+// a > b.
+//
+// Pointers are dereferenced, and nil pointers sort before all other values.
+// Values of different named types are compared by their underlying kind.
+func Compare(a, b any) int {
+	av, aok := deref(a)
+	bv, bok := deref(b)
+	switch {
+	case !aok && !bok:
+		return 0
+	case !aok:
+		return -1
+	case !bok:
+		return 1
+	}
+
+	if at, ok := av.(time.Time); ok {
+		if bt, ok := bv.(time.Time); ok {
+			return at.Compare(bt)
+		}
+	}
+
+	ar, br := reflect.ValueOf(av), reflect.ValueOf(bv)
+	switch {
+	case ar.CanInt() && br.CanInt():
+		return compare(ar.Int(), br.Int())
+	case ar.CanUint() && br.CanUint():
+		return compare(ar.Uint(), br.Uint())
+	case ar.CanInt() && br.CanUint():
+		if ar.Int() < 0 {
+			return -1
+		}
+		return compare(uint64(ar.Int()), br.Uint())
+	case ar.CanUint() && br.CanInt():
+		return -Compare(bv, av)
+	case ar.CanFloat() && br.CanFloat():
+		return compare(ar.Float(), br.Float())
+	case ar.Kind() == reflect.String && br.Kind() == reflect.String:
+		return strings.Compare(ar.String(), br.String())
+	case ar.Kind() == reflect.Bool && br.Kind() == reflect.Bool:
+		return compare(boolInt(ar.Bool()), boolInt(br.Bool()))
+	}
+
+	if ar.Type() == br.Type() && ar.Comparable() && ar.Equal(br) {
+		return 0
+	}
+
+	// not orderable, fall back to comparing the string representations
+	return strings.Compare(fmt.Sprint(av), fmt.Sprint(bv))
+}
+
+func compare[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// deref dereferences v, if it is a pointer, returning false if it is nil.
+func deref(v any) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+
+	return rv.Interface(), true
+}
+
+func contains[T any](s []T, v any) bool {
+	for _, t := range s {
+		if Compare(t, v) == 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toString(v any) string {
+	return reflect.ValueOf(v).String()
+}
+
+func joinFields(fields []any) string {
+	var b strings.Builder
+
+	for _, field := range fields {
+		f, ok := deref(field)
+		if !ok {
+			continue
+		}
+
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(toString(f))
+	}
+
+	return b.String()
+}
+
+// Paginate returns at most limit elements of s, starting at offset.
+// If limit is 0, all elements starting at offset are returned.
+func Paginate[T any](s []T, offset, limit int) []T {
+	if offset >= len(s) {
+		return nil
+	}
+
+	s = s[max(offset, 0):]
+	if limit > 0 && limit < len(s) {
+		s = s[:limit]
+	}
+
+	return s
+}
+
+// PaginateCursor returns at most limit elements of the sorted s that are
+// after the cursor after and before the cursor before, and the cursor of the
+// next page, or an empty string if there are no more elements after the page
+// and before before.
+//
+// If only before is set, the last limit elements before it are returned.
+//
+// Cursors are decoded using decode and created using encode, and cmp must be
+// the function s is sorted by.
+func PaginateCursor[T any](
+	s []T, after, before string, limit int,
+	decode func(cursor string) (T, error), encode func(T) (string, error), cmp func(a, b T) int,
+) (page []T, next string, err error) {
+	start, end := 0, len(s)
+	if after != "" {
+		c, err := decode(after)
+		if err != nil {
+			return nil, "", fmt.Errorf("memutil: invalid after cursor: %w", err)
+		}
+
+		var found bool
+		if start, found = slices.BinarySearchFunc(s, c, cmp); found {
+			start++
+		}
+	}
+	if before != "" {
+		c, err := decode(before)
+		if err != nil {
+			return nil, "", fmt.Errorf("memutil: invalid before cursor: %w", err)
+		}
+
+		end, _ = slices.BinarySearchFunc(s, c, cmp)
+	}
+
+	if start >= end {
+		return nil, "", nil
+	}
+
+	if limit <= 0 || end-start <= limit {
+		return s[start:end], "", nil
+	} else if before != "" && after == "" {
+		return s[end-limit : end], "", nil
+	}
+
+	end = start + limit
+	if next, err = encode(s[end-1]); err != nil {
+		return nil, "", err
+	}
+
+	return s[start:end], next, nil
+}
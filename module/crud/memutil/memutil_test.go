@@ -0,0 +1,173 @@
+package memutil
+
+import (
+	"errors"
+	"github.com/aarondl/opt/omit"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCompare(t *testing.T) {
+	type myInt int
+	type myString string
+
+	one, two := 1, 2
+	var nilInt *int
+	now := time.Now()
+
+	testCases := []struct {
+		name   string
+		a, b   any
+		expect int
+	}{
+		{name: "int less", a: 1, b: 2, expect: -1},
+		{name: "int equal", a: 2, b: 2, expect: 0},
+		{name: "int greater", a: 3, b: 2, expect: 1},
+		{name: "int and int8", a: int8(3), b: 3, expect: 0},
+		{name: "named int", a: myInt(1), b: 2, expect: -1},
+		{name: "uint and uint64", a: uint(3), b: uint64(2), expect: 1},
+		{name: "negative int and uint", a: -1, b: uint(0), expect: -1},
+		{name: "int and uint", a: 2, b: uint(2), expect: 0},
+		{name: "uint and negative int", a: uint(0), b: -1, expect: 1},
+		{name: "uint and int", a: uint(1), b: 2, expect: -1},
+		{name: "large uint and int", a: uint64(1 << 63), b: 1<<63 - 1, expect: 1},
+		{name: "float", a: 1.5, b: float32(2.5), expect: -1},
+		{name: "string", a: "b", b: myString("a"), expect: 1},
+		{name: "bool", a: false, b: true, expect: -1},
+		{name: "pointers", a: &one, b: &two, expect: -1},
+		{name: "pointer and value", a: &two, b: 2, expect: 0},
+		{name: "nil pointer and value", a: nilInt, b: 0, expect: -1},
+		{name: "value and nil pointer", a: 0, b: nilInt, expect: 1},
+		{name: "nil pointers", a: nilInt, b: (*string)(nil), expect: 0},
+		{name: "nil and nil pointer", a: nil, b: nilInt, expect: 0},
+		{name: "time before", a: now, b: now.Add(time.Second), expect: -1},
+		{name: "time equal", a: now, b: now.UTC(), expect: 0},
+		{name: "time pointer after", a: ptr(now.Add(time.Second)), b: now, expect: 1},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := Compare(c.a, c.b); actual != c.expect {
+				t.Errorf("Compare(%#v, %#v) = %d, expected %d", c.a, c.b, actual, c.expect)
+			}
+		})
+	}
+}
+
+func TestNotIn(t *testing.T) {
+	testCases := []struct {
+		name   string
+		v      omit.Val[[]int]
+		field  any
+		expect bool
+	}{
+		{name: "unset", v: omit.Val[[]int]{}, field: 1, expect: true},
+		{name: "empty", v: omit.From([]int{}), field: 1, expect: true},
+		{name: "empty nil field", v: omit.From([]int{}), field: (*int)(nil), expect: true},
+		{name: "contained", v: omit.From([]int{1, 2}), field: 2, expect: false},
+		{name: "not contained", v: omit.From([]int{1, 2}), field: 3, expect: true},
+		{name: "nil field", v: omit.From([]int{1, 2}), field: (*int)(nil), expect: false},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := NotIn(c.v, c.field); actual != c.expect {
+				t.Errorf("NotIn(%v, %v) = %t, expected %t", c.v, c.field, actual, c.expect)
+			}
+		})
+	}
+}
+
+func TestContainsAll(t *testing.T) {
+	testCases := []struct {
+		name   string
+		v      omit.Val[[]string]
+		field  any
+		expect bool
+	}{
+		{name: "unset", v: omit.Val[[]string]{}, field: []string{"a"}, expect: true},
+		{name: "empty", v: omit.From([]string{}), field: []string{"a"}, expect: true},
+		{name: "empty empty field", v: omit.From([]string{}), field: []string{}, expect: true},
+		{name: "empty nil field", v: omit.From([]string{}), field: (*[]string)(nil), expect: true},
+		{name: "all", v: omit.From([]string{"a", "b"}), field: []string{"b", "c", "a"}, expect: true},
+		{name: "some", v: omit.From([]string{"a", "b"}), field: []string{"a", "c"}, expect: false},
+		{name: "empty field", v: omit.From([]string{"a"}), field: []string{}, expect: false},
+		{name: "nil field", v: omit.From([]string{"a"}), field: (*[]string)(nil), expect: false},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := ContainsAll(c.v, c.field); actual != c.expect {
+				t.Errorf("ContainsAll(%v, %v) = %t, expected %t", c.v, c.field, actual, c.expect)
+			}
+		})
+	}
+}
+
+func TestPaginateCursor(t *testing.T) {
+	s := []int{1, 2, 3, 4, 5}
+	decode := func(cursor string) (int, error) { return strconv.Atoi(cursor) }
+	encode := func(i int) (string, error) { return strconv.Itoa(i), nil }
+	cmp := func(a, b int) int { return a - b }
+
+	testCases := []struct {
+		name          string
+		after, before string
+		limit         int
+		expectPage    []int
+		expectNext    string
+	}{
+		{name: "no cursors", expectPage: []int{1, 2, 3, 4, 5}},
+		{name: "no cursors limit", limit: 2, expectPage: []int{1, 2}, expectNext: "2"},
+		{name: "negative limit", limit: -1, expectPage: []int{1, 2, 3, 4, 5}},
+		{name: "limit equal to len", limit: 5, expectPage: []int{1, 2, 3, 4, 5}},
+		{name: "limit greater than len", limit: 6, expectPage: []int{1, 2, 3, 4, 5}},
+		{name: "after", after: "2", expectPage: []int{3, 4, 5}},
+		{name: "after limit", after: "2", limit: 2, expectPage: []int{3, 4}, expectNext: "4"},
+		{name: "after limit last page", after: "3", limit: 2, expectPage: []int{4, 5}},
+		{name: "after missing element", after: "0", limit: 1, expectPage: []int{1}, expectNext: "1"},
+		{name: "after last", after: "5", limit: 2},
+		{name: "before", before: "4", expectPage: []int{1, 2, 3}},
+		{name: "before limit", before: "4", limit: 2, expectPage: []int{2, 3}},
+		{name: "before first", before: "1", limit: 2},
+		{name: "both", after: "1", before: "5", expectPage: []int{2, 3, 4}},
+		{name: "both limit", after: "1", before: "5", limit: 2, expectPage: []int{2, 3}, expectNext: "3"},
+		{name: "both limit last page", after: "2", before: "5", limit: 2, expectPage: []int{3, 4}},
+		{name: "both empty", after: "3", before: "4", limit: 2},
+		{name: "both reversed", after: "4", before: "2", limit: 2},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			page, next, err := PaginateCursor(s, c.after, c.before, c.limit, decode, encode, cmp)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if !slices.Equal(page, c.expectPage) {
+				t.Errorf("page is %v, expected %v", page, c.expectPage)
+			}
+			if next != c.expectNext {
+				t.Errorf("next is %q, expected %q", next, c.expectNext)
+			}
+		})
+	}
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		decodeErr := errors.New("invalid")
+		decode := func(string) (int, error) { return 0, decodeErr }
+
+		for _, cursors := range [][2]string{{"a", ""}, {"", "a"}} {
+			_, _, err := PaginateCursor(s, cursors[0], cursors[1], 0, decode, encode, cmp)
+			if !errors.Is(err, decodeErr) {
+				t.Errorf("error for cursors %q is %v, expected it to wrap %v", cursors, err, decodeErr)
+			}
+		}
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
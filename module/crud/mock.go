@@ -0,0 +1,182 @@
+package crud
+
+import (
+	"fmt"
+	"github.com/mavolin/repogen/internal/goimports"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const mockOutName = "crud_mock.repogen.go"
+
+var mockTpl = template.Must(template.ParseFS(templates, "mock.gotpl"))
+
+type (
+	MockData struct {
+		Package string
+		Mocks   []Mock
+	}
+
+	Mock struct {
+		Name      string
+		Interface string
+		// Embeds are the embedded mocks and interfaces.
+		Embeds  []string
+		Methods []MockMethod
+	}
+
+	MockMethod struct {
+		Name     string
+		Params   []Param
+		Results  []Param
+		Variadic bool
+	}
+)
+
+// generateMock generates mocks for all interfaces in the generated crud file.
+func generateMock(pkgName string) error {
+	f, err := parseOut()
+	if err != nil {
+		return err
+	}
+
+	data := MockData{Package: pkgName, Mocks: findMocks(f)}
+
+	out, err := os.Create(mockOutName)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := mockTpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = mockTpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+// parseOut parses the generated crud file.
+func parseOut() (*ast.File, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, outName, nil, 0)
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+
+	return f, nil
+}
+
+// findInterfaces returns the names of the interfaces declared in f, in
+// order of declaration, and the interfaces mapped by their names.
+func findInterfaces(f *ast.File) ([]string, map[string]*ast.InterfaceType) {
+	ifaces := make(map[string]*ast.InterfaceType)
+	var names []string
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		spec, ok := n.(*ast.TypeSpec)
+		if !ok {
+			return true
+		}
+
+		if iface, ok := spec.Type.(*ast.InterfaceType); ok {
+			ifaces[spec.Name.Name] = iface
+			names = append(names, spec.Name.Name)
+		}
+		return false
+	})
+
+	return names, ifaces
+}
+
+func findMocks(f *ast.File) []Mock {
+	names, ifaces := findInterfaces(f)
+
+	mocks := make([]Mock, 0, len(names))
+	for _, name := range names {
+		m := Mock{Name: "Mock" + name, Interface: name}
+
+		for _, field := range ifaces[name].Methods.List {
+			ftyp, ok := field.Type.(*ast.FuncType)
+			if !ok {
+				embed := types.ExprString(field.Type)
+				if _, ok := ifaces[embed]; ok {
+					embed = "Mock" + embed
+				}
+				m.Embeds = append(m.Embeds, embed)
+				continue
+			}
+
+			for _, ident := range field.Names {
+				m.Methods = append(m.Methods, mockMethod(ident.Name, ftyp))
+			}
+		}
+
+		mocks = append(mocks, m)
+	}
+
+	return mocks
+}
+
+func mockMethod(name string, ftyp *ast.FuncType) MockMethod {
+	mm := MockMethod{
+		Name:   name,
+		Params: mockParams(ftyp.Params, "p"),
+	}
+	if ftyp.Results != nil {
+		mm.Results = mockParams(ftyp.Results, "r")
+	}
+
+	if len(mm.Params) > 0 {
+		last := &mm.Params[len(mm.Params)-1]
+		if strings.HasPrefix(last.Type, "...") {
+			mm.Variadic = true
+		}
+	}
+
+	return mm
+}
+
+// mockParams returns the params in fl, naming unnamed params using prefix.
+func mockParams(fl *ast.FieldList, prefix string) []Param {
+	ps := make([]Param, 0, fl.NumFields())
+
+	for _, field := range fl.List {
+		typ := types.ExprString(field.Type)
+		if len(field.Names) == 0 {
+			ps = append(ps, Param{Name: fmt.Sprintf("%s%d", prefix, len(ps)), Type: typ})
+			continue
+		}
+
+		for _, ident := range field.Names {
+			pname := ident.Name
+			if pname == "_" {
+				pname = fmt.Sprintf("%s%d", prefix, len(ps))
+			}
+			ps = append(ps, Param{Name: pname, Type: typ})
+		}
+	}
+
+	return ps
+}
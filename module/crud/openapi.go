@@ -0,0 +1,542 @@
+package crud
+
+import (
+	"encoding/json"
+	"github.com/mavolin/repogen/internal/pkgutil"
+	"github.com/mavolin/repogen/internal/util"
+	"github.com/mavolin/repogen/module/search"
+	"github.com/mavolin/repogen/module/setter"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const openAPIOutName = "openapi.repogen.json"
+
+type (
+	// OpenAPI is an OpenAPI 3.1 document.
+	OpenAPI struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths,omitempty"`
+		Components OpenAPIComponents                       `json:"components"`
+	}
+
+	OpenAPIInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+
+	OpenAPIComponents struct {
+		Schemas map[string]*OpenAPISchema `json:"schemas"`
+	}
+
+	OpenAPIOperation struct {
+		OperationID string                     `json:"operationId"`
+		Summary     string                     `json:"summary"`
+		Parameters  []OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody        `json:"requestBody,omitempty"`
+		Responses   map[string]OpenAPIResponse `json:"responses"`
+	}
+
+	OpenAPIParameter struct {
+		Name        string         `json:"name"`
+		In          string         `json:"in"`
+		Description string         `json:"description,omitempty"`
+		Required    bool           `json:"required,omitempty"`
+		Style       string         `json:"style,omitempty"`
+		Explode     bool           `json:"explode,omitempty"`
+		Schema      *OpenAPISchema `json:"schema"`
+	}
+
+	OpenAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]OpenAPIMediaType `json:"content"`
+	}
+
+	OpenAPIResponse struct {
+		Description string                      `json:"description"`
+		Headers     map[string]OpenAPIHeader    `json:"headers,omitempty"`
+		Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	OpenAPIHeader struct {
+		Description string         `json:"description,omitempty"`
+		Schema      *OpenAPISchema `json:"schema"`
+	}
+
+	OpenAPIMediaType struct {
+		Schema *OpenAPISchema `json:"schema"`
+	}
+
+	OpenAPISchema struct {
+		Ref         string `json:"$ref,omitempty"`
+		Description string `json:"description,omitempty"`
+		// Type is either a string or, for nullable types, a []string.
+		Type                 any                       `json:"type,omitempty"`
+		Format               string                    `json:"format,omitempty"`
+		Enum                 []string                  `json:"enum,omitempty"`
+		Items                *OpenAPISchema            `json:"items,omitempty"`
+		Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+		Required             []string                  `json:"required,omitempty"`
+		AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+		OneOf                []*OpenAPISchema          `json:"oneOf,omitempty"`
+	}
+)
+
+// generateOpenAPI generates an OpenAPI document with schemas for all entities
+// and paths for the http handlers of the entities that have them.
+func generateOpenAPI(pkg *packages.Package, es []Entity, title string) error {
+	if title == "" {
+		title = pkg.Name
+	}
+
+	doc := OpenAPI{
+		OpenAPI:    "3.1.0",
+		Info:       OpenAPIInfo{Title: title, Version: "1.0.0"},
+		Paths:      make(map[string]map[string]*OpenAPIOperation),
+		Components: OpenAPIComponents{Schemas: make(map[string]*OpenAPISchema)},
+	}
+
+	refs := make(map[string]bool, len(es))
+	for _, e := range es {
+		refs[e.Singular] = true
+	}
+
+	for _, e := range es {
+		obj := pkg.Types.Scope().Lookup(e.Singular)
+		s := pkgutil.ElemType(obj.Type()).(*types.Struct)
+
+		doc.Components.Schemas[e.Singular] = openAPIStruct(pkg, refs, s)
+
+		setterSchema, err := openAPISetter(pkg, refs, obj, s)
+		if err != nil {
+			return err
+		}
+		doc.Components.Schemas[e.Singular+"Setter"] = setterSchema
+
+		se, err := search.FindEntity(pkg, obj)
+		if err != nil {
+			return err
+		}
+		if se != nil {
+			doc.Components.Schemas[se.SearchType] = openAPISearch(pkg, refs, s, se)
+		}
+
+		if e.Paginate != "" {
+			doc.Components.Schemas[e.PageType] = openAPIPage(e)
+		}
+
+		if e.HTTPPath != "" {
+			addOpenAPIPaths(pkg, refs, doc.Paths, e, s, se)
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := os.WriteFile(openAPIOutName, append(data, '\n'), 0o644); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+// openAPIType returns the schema of t, as encoded by encoding/json.
+//
+// refs are the names of the types in pkg that have their own schema.
+func openAPIType(pkg *packages.Package, refs map[string]bool, t types.Type) *OpenAPISchema {
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time" {
+			return &OpenAPISchema{Type: "string", Format: "date-time"}
+		}
+
+		if obj.Pkg() == pkg.Types && refs[obj.Name()] {
+			return &OpenAPISchema{Ref: "#/components/schemas/" + obj.Name()}
+		}
+
+		if hasMethod(t, "MarshalJSON") {
+			return &OpenAPISchema{}
+		} else if hasMethod(t, "MarshalText") {
+			return &OpenAPISchema{Type: "string"}
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		return openAPINullable(openAPIType(pkg, refs, u.Elem()))
+	case *types.Basic:
+		info := u.Info()
+		switch {
+		case info&types.IsString != 0:
+			return &OpenAPISchema{Type: "string"}
+		case info&types.IsBoolean != 0:
+			return &OpenAPISchema{Type: "boolean"}
+		case info&types.IsInteger != 0:
+			switch u.Kind() {
+			case types.Int8, types.Int16, types.Int32, types.Uint8, types.Uint16:
+				return &OpenAPISchema{Type: "integer", Format: "int32"}
+			default:
+				return &OpenAPISchema{Type: "integer", Format: "int64"}
+			}
+		case info&types.IsFloat != 0:
+			if u.Kind() == types.Float32 {
+				return &OpenAPISchema{Type: "number", Format: "float"}
+			}
+			return &OpenAPISchema{Type: "number", Format: "double"}
+		}
+	case *types.Slice:
+		if basic, ok := u.Elem().(*types.Basic); ok && basic.Kind() == types.Byte {
+			return openAPINullable(&OpenAPISchema{Type: "string", Format: "byte"})
+		}
+		return openAPINullable(&OpenAPISchema{Type: "array", Items: openAPIType(pkg, refs, u.Elem())})
+	case *types.Array:
+		return &OpenAPISchema{Type: "array", Items: openAPIType(pkg, refs, u.Elem())}
+	case *types.Map:
+		return openAPINullable(&OpenAPISchema{Type: "object", AdditionalProperties: openAPIType(pkg, refs, u.Elem())})
+	case *types.Struct:
+		return openAPIStruct(pkg, refs, u)
+	}
+
+	return &OpenAPISchema{}
+}
+
+// hasMethod reports whether the method set of *t contains the given method.
+func hasMethod(t types.Type, method string) bool {
+	m, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, method)
+	_, ok := m.(*types.Func)
+	return ok
+}
+
+// openAPINullable returns s, allowing null values.
+func openAPINullable(s *OpenAPISchema) *OpenAPISchema {
+	switch typ := s.Type.(type) {
+	case string:
+		s.Type = []string{typ, "null"}
+		return s
+	case nil:
+		if s.Ref == "" {
+			return s // already allows any value
+		}
+		return &OpenAPISchema{OneOf: []*OpenAPISchema{s, {Type: "null"}}}
+	default:
+		return s
+	}
+}
+
+// openAPIStruct returns the schema of s, as encoded by encoding/json.
+func openAPIStruct(pkg *packages.Package, refs map[string]bool, s *types.Struct) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+
+		name, opts, _ := strings.Cut(reflect.StructTag(s.Tag(i)).Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		if f.Embedded() && name == "" {
+			if es, ok := pkgutil.ElemType(f.Type()).(*types.Struct); ok {
+				embedded := openAPIStruct(pkg, refs, es)
+				for k, v := range embedded.Properties {
+					schema.Properties[k] = v
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+				continue
+			}
+		}
+
+		if !f.Exported() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name()
+		}
+
+		schema.Properties[name] = openAPIType(pkg, refs, f.Type())
+		if !strings.Contains(","+opts+",", ",omitempty,") && !strings.Contains(","+opts+",", ",omitzero,") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// openAPISetter returns the schema of the setter of the entity s.
+//
+// All properties are optional, and those of omitnull.Val fields are nullable.
+func openAPISetter(pkg *packages.Package, refs map[string]bool, obj types.Object, s *types.Struct) (*OpenAPISchema, error) {
+	fields, err := setter.ListFields(pkg, obj, s)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema, len(fields))}
+
+	for _, sf := range fields {
+		f := s.Field(fieldIndex(s, sf.FieldName))
+
+		var prop *OpenAPISchema
+		switch tag := util.ParseStructTag(s.Tag(fieldIndex(s, sf.FieldName))); {
+		case tag["rel"] != "":
+			prop = &OpenAPISchema{}
+			if named, ok := pkgutil.ElemType(f.Type()).(*types.Named); ok && refs[named.Obj().Name()] {
+				prop.Ref = "#/components/schemas/" + named.Obj().Name() + "Setter"
+				if _, ok := f.Type().Underlying().(*types.Slice); ok {
+					prop = &OpenAPISchema{Type: "array", Items: prop}
+				}
+			}
+		case sf.Converted:
+			prop = &OpenAPISchema{}
+		default:
+			t := f.Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			prop = openAPIType(pkg, refs, t)
+		}
+
+		if strings.HasPrefix(sf.Type, "omitnull.") {
+			prop = openAPINullable(prop)
+		} else if typ, ok := prop.Type.([]string); ok {
+			prop.Type = typ[0] // omit.Val doesn't accept null
+		}
+
+		schema.Properties[sf.Name] = prop
+	}
+
+	return schema, nil
+}
+
+// openAPISearch returns the schema of the search struct se of the entity s,
+// as parsed from a query.
+func openAPISearch(pkg *packages.Package, refs map[string]bool, s *types.Struct, se *search.Entity) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema, len(se.Fields))}
+
+	for _, f := range se.Fields {
+		if f.QueryFunc == "" {
+			continue
+		}
+
+		var prop *OpenAPISchema
+		switch {
+		case f.Name == "Sort":
+			keys := make([]string, len(se.SortFields))
+			for i, sf := range se.SortFields {
+				keys[i] = sf.Key
+			}
+
+			prop = &OpenAPISchema{
+				Type: "string",
+				Description: "Comma-separated list of the keys to sort by, each optionally prefixed by - to sort " +
+					"descending: " + strings.Join(keys, ", "),
+			}
+		case f.QueryParser == "ParseDeletedFilter":
+			prop = &OpenAPISchema{Type: "string", Enum: []string{"exclude", "include", "only"}}
+		case f.Op == "isnull":
+			prop = &OpenAPISchema{Type: "boolean"}
+		case f.FieldName != "" && fieldIndex(s, f.FieldName) >= 0:
+			t := s.Field(fieldIndex(s, f.FieldName)).Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+
+			prop = openAPIType(pkg, refs, t)
+			if f.Op == "in" || f.Op == "notin" {
+				prop = &OpenAPISchema{Type: "array", Items: prop}
+			} else if typ, ok := prop.Type.([]string); ok {
+				prop.Type = typ[0] // slices can't be null in queries
+			}
+		default:
+			switch {
+			case strings.HasPrefix(f.QueryParser, "queryutil.ParseInt["),
+				strings.HasPrefix(f.QueryParser, "queryutil.ParseUint["):
+				prop = &OpenAPISchema{Type: "integer"}
+			case strings.HasPrefix(f.QueryParser, "queryutil.ParseFloat["):
+				prop = &OpenAPISchema{Type: "number"}
+			case strings.HasPrefix(f.QueryParser, "queryutil.ParseBool["):
+				prop = &OpenAPISchema{Type: "boolean"}
+			default:
+				prop = &OpenAPISchema{Type: "string"}
+			}
+		}
+
+		schema.Properties[f.QueryKey] = prop
+	}
+
+	return schema
+}
+
+func openAPIPage(e Entity) *OpenAPISchema {
+	schema := &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"Items": {Type: "array", Items: &OpenAPISchema{Ref: "#/components/schemas/" + e.Singular}},
+			"Total": {Type: "integer"},
+		},
+		Required: []string{"Items", "Total"},
+	}
+
+	if e.Paginate == "cursor" {
+		schema.Properties["NextCursor"] = &OpenAPISchema{Type: "string"}
+		schema.Required = append(schema.Required, "NextCursor")
+	}
+
+	return schema
+}
+
+// addOpenAPIPaths adds the paths served by e's http handler to paths.
+func addOpenAPIPaths(
+	pkg *packages.Package, refs map[string]bool, paths map[string]map[string]*OpenAPIOperation,
+	e Entity, s *types.Struct, se *search.Entity,
+) {
+	ref := func(name string) *OpenAPISchema {
+		return &OpenAPISchema{Ref: "#/components/schemas/" + name}
+	}
+	content := func(schema *OpenAPISchema) map[string]OpenAPIMediaType {
+		return map[string]OpenAPIMediaType{"application/json": {Schema: schema}}
+	}
+	responses := func(success string, resp OpenAPIResponse, statuses ...string) map[string]OpenAPIResponse {
+		rs := map[string]OpenAPIResponse{success: resp}
+		for _, status := range statuses {
+			rs[status] = OpenAPIResponse{Description: openAPIStatusText[status]}
+		}
+		if e.ActorType() != "" && success != "200" {
+			rs["401"] = OpenAPIResponse{Description: openAPIStatusText["401"]}
+		}
+		return rs
+	}
+
+	list := make(map[string]*OpenAPIOperation)
+	single := make(map[string]*OpenAPIOperation)
+
+	pkParams := make([]OpenAPIParameter, len(e.PKs))
+	for i, pk := range e.PKs {
+		if e.Key != nil {
+			pkParams[i] = OpenAPIParameter{
+				Name:        pk.Name,
+				In:          "path",
+				Description: "The query-escaped pks of the " + e.Singular + ", separated by commas.",
+				Required:    true,
+				Schema:      &OpenAPISchema{Type: "string"},
+			}
+			continue
+		}
+
+		pkParams[i] = OpenAPIParameter{
+			Name:     pk.Name,
+			In:       "path",
+			Required: true,
+			Schema:   openAPIType(pkg, refs, s.Field(fieldIndex(s, pk.Field)).Type()),
+		}
+	}
+
+	versionParams := pkParams
+	if e.VersionType != "" {
+		versionParams = append(append([]OpenAPIParameter(nil), pkParams...), OpenAPIParameter{
+			Name:        "If-Match",
+			In:          "header",
+			Description: "The version of the " + e.Singular + ", as returned in the ETag header.",
+			Required:    true,
+			Schema:      &OpenAPISchema{Type: "string"},
+		})
+	}
+
+	if e.Search && se != nil {
+		res := &OpenAPISchema{Type: "array", Items: ref(e.Singular)}
+		if e.Paginate != "" {
+			res = ref(e.PageType)
+		}
+
+		list["get"] = &OpenAPIOperation{
+			OperationID: e.Plural,
+			Summary:     "Search " + e.Plural,
+			Parameters: []OpenAPIParameter{{
+				Name:    "search",
+				In:      "query",
+				Style:   "form",
+				Explode: true,
+				Schema:  ref(se.SearchType),
+			}},
+			Responses: responses("200", OpenAPIResponse{Description: "The matching " + e.Plural, Content: content(res)}, "400"),
+		}
+	}
+
+	if e.Create {
+		created := OpenAPIResponse{Description: "The " + e.Singular + " was created"}
+		if len(e.PKs) == 1 {
+			created.Headers = map[string]OpenAPIHeader{
+				"Location": {Description: "The path of the created " + e.Singular, Schema: &OpenAPISchema{Type: "string"}},
+			}
+		}
+
+		list["post"] = &OpenAPIOperation{
+			OperationID: "Create" + e.Singular,
+			Summary:     "Create a " + e.Singular,
+			RequestBody: &OpenAPIRequestBody{Required: true, Content: content(ref(e.Singular + "Setter"))},
+			Responses:   responses("201", created, "400", "409"),
+		}
+	}
+
+	if e.Get {
+		ok := OpenAPIResponse{Description: "The " + e.Singular, Content: content(ref(e.Singular))}
+		if e.VersionType != "" {
+			ok.Headers = map[string]OpenAPIHeader{
+				"ETag": {Description: "The version of the " + e.Singular, Schema: &OpenAPISchema{Type: "string"}},
+			}
+		}
+
+		single["get"] = &OpenAPIOperation{
+			OperationID: e.Singular,
+			Summary:     "Get a " + e.Singular,
+			Parameters:  pkParams,
+			Responses:   responses("200", ok, "400", "404"),
+		}
+	}
+
+	statuses := []string{"400", "404", "409"}
+	if e.VersionType != "" {
+		statuses = append(statuses, "428")
+	}
+
+	if e.Edit {
+		single["patch"] = &OpenAPIOperation{
+			OperationID: "Edit" + e.Singular,
+			Summary:     "Edit a " + e.Singular,
+			Parameters:  versionParams,
+			RequestBody: &OpenAPIRequestBody{Required: true, Content: content(ref(e.Singular + "Setter"))},
+			Responses:   responses("204", OpenAPIResponse{Description: "The " + e.Singular + " was edited"}, statuses...),
+		}
+	}
+
+	if e.Delete {
+		single["delete"] = &OpenAPIOperation{
+			OperationID: "Delete" + e.Singular,
+			Summary:     "Delete a " + e.Singular,
+			Parameters:  versionParams,
+			Responses:   responses("204", OpenAPIResponse{Description: "The " + e.Singular + " was deleted"}, statuses...),
+		}
+	}
+
+	if len(list) > 0 {
+		paths[e.HTTPPath] = list
+	}
+	if len(single) > 0 {
+		paths[HTTPEntity{Entity: e}.PKPath()] = single
+	}
+}
+
+var openAPIStatusText = map[string]string{
+	"400": "Bad Request",
+	"401": "Unauthorized",
+	"404": "Not Found",
+	"409": "Conflict",
+	"428": "Precondition Required",
+}
@@ -0,0 +1,772 @@
+package crud
+
+import (
+	"fmt"
+	"github.com/iancoleman/strcase"
+	"github.com/mavolin/repogen/internal/goimports"
+	"github.com/mavolin/repogen/internal/pkgutil"
+	"github.com/mavolin/repogen/module/search"
+	"github.com/mavolin/repogen/module/setter"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const (
+	protoOutName      = "crud.repogen.proto"
+	protoConvOutName  = "crud_proto.repogen.go"
+	timestampType     = "google.protobuf.Timestamp"
+	timestampGoType   = "*timestamppb.Timestamp"
+	deletedFilterType = "DeletedFilter"
+)
+
+var (
+	protoTpl     = template.Must(template.ParseFS(templates, "proto.gotpl"))
+	protoConvTpl = template.Must(template.ParseFS(templates, "proto_conv.gotpl"))
+)
+
+type (
+	ProtoData struct {
+		Package string
+		// ProtoPackage is the package of the proto file.
+		ProtoPackage string
+		// GoPackage is the import path of the Go package generated from the
+		// proto file.
+		GoPackage string
+		Entities  []ProtoEntity
+		Messages  []ProtoMessage
+		RPCs      []ProtoRPC
+		// Timestamp and Empty indicate whether the respective well-known
+		// types are used.
+		Timestamp, Empty bool
+	}
+
+	ProtoEntity struct {
+		Entity
+
+		Fields       []ProtoField
+		SetterFields []ProtoField
+		// Searchable indicates whether the entity has a search struct.
+		Searchable   bool
+		SearchFields []ProtoField
+		// SortParseFunc is the function parsing the sort field of the search
+		// struct, or empty if the search struct has no sort field.
+		SortParseFunc string
+		// LoadFields are the fields of the entity's Load type.
+		LoadFields []ProtoField
+	}
+
+	ProtoMessage struct {
+		Name   string
+		Fields []ProtoField
+		// Unsupported are the Go fields that can't be represented in proto.
+		Unsupported []ProtoField
+	}
+
+	ProtoRPC struct {
+		Name     string
+		Request  string
+		Response string
+	}
+
+	ProtoField struct {
+		// GoField is the name of the field in the Go struct.
+		GoField string
+		// Name is the name of the proto field.
+		Name string
+		// GoName is the name of the field in the Go struct generated by
+		// protoc-gen-go.
+		GoName string
+		Type   string
+		// Label is either empty, optional or repeated.
+		Label string
+		Num   int
+
+		// GoType is the Go type of the field or, for pointers and slices,
+		// of their elements.
+		GoType string
+		// PBGoType is the Go type of the field or, for pointers and slices,
+		// of their elements, as generated by protoc-gen-go.
+		PBGoType   string
+		Ptr, Slice bool
+		// Nullable indicates whether the field of the Go setter or search
+		// struct is of type omitnull.Val.
+		Nullable bool
+		// Plain indicates whether the field of the Go search struct is not
+		// wrapped in an omit.Val or omitnull.Val.
+		Plain bool
+		// Sort indicates whether this is the sort field of a search struct.
+		Sort bool
+		// Reason is the reason the field is not supported.
+		Reason string
+	}
+)
+
+// generateProto generates the proto file and the conversions between the
+// entities and the generated messages.
+//
+// args are the args of the proto directive, i.e. the optional name of the
+// proto package, followed by the import path of the Go package generated
+// from the proto file.
+// If no proto package is given, it is derived from pkg's import path.
+func generateProto(pkg *packages.Package, es []Entity, args string) error {
+	protoPackage, goPackage, ok := strings.Cut(strings.TrimSpace(args), " ")
+	if !ok {
+		protoPackage, goPackage = protoPackageName(pkg.PkgPath), protoPackage
+	}
+
+	goPackage = strings.TrimSpace(goPackage)
+	if goPackage == "" {
+		return wrapErr(fmt.Errorf("proto directive requires the import path of the generated Go package"))
+	}
+
+	data := ProtoData{Package: pkg.Name, ProtoPackage: protoPackage, GoPackage: goPackage}
+
+	for _, e := range es {
+		pe, err := protoEntity(pkg, e)
+		if err != nil {
+			return err
+		}
+
+		data.Entities = append(data.Entities, pe)
+		data.addMessages(pkg, pe)
+	}
+
+	for _, m := range data.Messages {
+		for _, f := range m.Fields {
+			data.Timestamp = data.Timestamp || f.Type == timestampType
+		}
+	}
+	for _, rpc := range data.RPCs {
+		data.Empty = data.Empty || rpc.Response == "google.protobuf.Empty"
+	}
+
+	if err := executeProto(protoOutName, protoTpl, data, false); err != nil {
+		return err
+	}
+
+	return executeProto(protoConvOutName, protoConvTpl, data, true)
+}
+
+func executeProto(name string, tpl *template.Template, data ProtoData, goFile bool) error {
+	out, err := os.Create(name)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if !goFile {
+		if err := tpl.Execute(out, data); err != nil {
+			return wrapErr(err)
+		}
+
+		return wrapErr(out.Close())
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := tpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = tpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+func protoEntity(pkg *packages.Package, e Entity) (ProtoEntity, error) {
+	obj := pkg.Types.Scope().Lookup(e.Singular)
+	s := pkgutil.ElemType(obj.Type()).(*types.Struct)
+
+	pe := ProtoEntity{Entity: e}
+
+	for i := 0; i < s.NumFields(); i++ {
+		if slices.Contains(e.Rels, s.Field(i).Name()) {
+			pe.Fields = append(pe.Fields, ProtoField{
+				GoField: s.Field(i).Name(),
+				Reason:  "Relations are loaded using " + e.Singular + "Load.",
+			})
+			continue
+		}
+
+		f, ok := newProtoField(pkg, s.Field(i).Name(), s.Field(i).Type())
+		if !ok {
+			return pe, unsupportedFieldErr(pkg, obj, s.Field(i).Name(), s.Field(i).Type())
+		}
+
+		pe.Fields = append(pe.Fields, f)
+	}
+
+	for _, rel := range e.Rels {
+		f, _ := newProtoField(pkg, rel, types.Typ[types.Bool])
+		pe.LoadFields = append(pe.LoadFields, f)
+	}
+
+	setterFields, err := setter.ListFields(pkg, obj, s)
+	if err != nil {
+		return pe, err
+	}
+
+	for _, sf := range setterFields {
+		t := s.Field(fieldIndex(s, sf.FieldName)).Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+
+		if sf.Converted {
+			pe.SetterFields = append(pe.SetterFields, ProtoField{
+				GoField: sf.Name,
+				Reason:  "Fields with a settyp or rel tag are not converted from proto.",
+			})
+			continue
+		}
+
+		f, ok := newProtoField(pkg, sf.Name, t)
+		if ok && strings.HasPrefix(sf.Type, "omitnull.") {
+			f.Nullable = true
+			ok = !f.Slice && f.setPtr()
+		}
+
+		if !ok {
+			return pe, unsupportedFieldErr(pkg, obj, sf.Name, t)
+		}
+
+		pe.SetterFields = append(pe.SetterFields, f)
+	}
+
+	se, err := search.FindEntity(pkg, obj)
+	if err != nil || se == nil {
+		return pe, err
+	}
+
+	pe.Searchable = true
+	for _, sf := range se.Fields {
+		if se.FilterType != "" && sf.Name == "Filter" {
+			pe.SearchFields = append(pe.SearchFields, ProtoField{
+				GoField: sf.Name,
+				Reason:  "Filters can't be combined in searches sent through the service.",
+			})
+			continue
+		}
+
+		f, ok := protoSearchField(pkg, s, se, sf)
+		if !ok {
+			return pe, objErr(pkg, obj, fmt.Sprintf("proto: %s.%s: %s cannot be represented in proto",
+				se.SearchType, sf.Name, sf.Type))
+		}
+
+		if f.Sort {
+			pe.SortParseFunc = se.SortParseFunc
+		}
+
+		pe.SearchFields = append(pe.SearchFields, f)
+	}
+
+	return pe, nil
+}
+
+func protoSearchField(pkg *packages.Package, s *types.Struct, se *search.Entity, sf search.Field) (ProtoField, bool) {
+	if sf.Name == "Sort" {
+		return ProtoField{
+			GoField: sf.Name, Name: "sort", GoName: "Sort", Type: "string", Label: "repeated", Sort: true,
+		}, true
+	}
+
+	var t types.Type
+	switch {
+	case sf.Op == "isnull":
+		t = types.Typ[types.Bool]
+	case sf.FieldName != "" && fieldIndex(s, sf.FieldName) >= 0:
+		t = s.Field(fieldIndex(s, sf.FieldName)).Type()
+		if ptr, ok := t.(*types.Pointer); ok {
+			t = ptr.Elem()
+		}
+
+		if sf.Op == "in" || sf.Op == "notin" {
+			t = types.NewSlice(t)
+		}
+	case sf.ValType() == deletedFilterType:
+		f := protoFieldName(sf.Name)
+		f.Type, f.GoType, f.PBGoType = "uint32", deletedFilterType, "uint32"
+		f.Plain = true
+		return f, true
+	default:
+		obj := types.Universe.Lookup(sf.ValType())
+		if obj == nil {
+			return ProtoField{}, false
+		}
+		t = obj.Type()
+	}
+
+	f, ok := newProtoField(pkg, sf.Name, t)
+	if !ok {
+		return f, false
+	}
+
+	f.Nullable = sf.IsNullable()
+	f.Plain = !strings.HasPrefix(sf.Type, "omit")
+	if !f.Plain && !f.Slice {
+		return f, f.setPtr()
+	}
+
+	return f, true
+}
+
+// newProtoField returns the proto field for the Go field with the given name
+// and type, and whether t can be represented in proto.
+func newProtoField(pkg *packages.Package, name string, t types.Type) (ProtoField, bool) {
+	f := protoFieldName(name)
+
+	if ptr, ok := t.(*types.Pointer); ok {
+		f.Ptr = true
+		t = ptr.Elem()
+	}
+
+	if slice, ok := t.Underlying().(*types.Slice); ok {
+		if basic, ok := slice.Elem().Underlying().(*types.Basic); !ok || basic.Kind() != types.Byte {
+			if f.Ptr {
+				return f, false
+			}
+
+			f.Slice = true
+			f.Label = "repeated"
+			t = slice.Elem()
+		}
+	}
+
+	f.GoType = pkgutil.NameInPackage(pkg, t)
+	if f.GoType == "" {
+		return f, false
+	}
+
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil &&
+		named.Obj().Pkg().Path() == "time" && named.Obj().Name() == "Time" {
+		f.Type, f.PBGoType = timestampType, timestampGoType
+		return f, true
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch u.Kind() {
+		case types.String:
+			f.Type = "string"
+		case types.Bool:
+			f.Type = "bool"
+		// smaller integers are not supported, as they can't be converted from
+		// their proto type without losing data
+		case types.Int32:
+			f.Type = "int32"
+		case types.Int, types.Int64:
+			f.Type = "int64"
+		case types.Uint32:
+			f.Type = "uint32"
+		case types.Uint, types.Uint64:
+			f.Type = "uint64"
+		case types.Float32:
+			f.Type, f.PBGoType = "float", "float32"
+		case types.Float64:
+			f.Type, f.PBGoType = "double", "float64"
+		default:
+			return f, false
+		}
+	case *types.Slice: // []byte
+		f.Type, f.PBGoType = "bytes", "[]byte"
+		if f.Ptr {
+			return f, false
+		}
+	default:
+		return f, false
+	}
+
+	if f.PBGoType == "" {
+		f.PBGoType = f.Type
+	}
+
+	if f.Ptr {
+		f.Label = "optional"
+	}
+
+	return f, true
+}
+
+// unsupportedFieldErr returns the error for the field of obj with the given
+// name and type t, which can't be represented in proto.
+func unsupportedFieldErr(pkg *packages.Package, obj types.Object, name string, t types.Type) error {
+	return objErr(pkg, obj, fmt.Sprintf("proto: %s: %s cannot be represented in proto",
+		name, types.TypeString(t, types.RelativeTo(pkg.Types))))
+}
+
+func protoFieldName(goName string) ProtoField {
+	name := strcase.ToSnake(goName)
+	return ProtoField{GoField: goName, Name: name, GoName: goCamelCase(name)}
+}
+
+// setPtr makes f an optional field, whose Go type is a pointer, and reports
+// whether this is possible.
+//
+// Optional bytes fields are not supported, as protoc-gen-go doesn't generate
+// pointers for them.
+func (f *ProtoField) setPtr() bool {
+	if f.Type == "bytes" {
+		return false
+	}
+
+	f.Ptr = true
+	if f.Type != timestampType {
+		f.Label = "optional"
+	}
+
+	return true
+}
+
+// addMessages adds the messages and rpcs of e.
+func (d *ProtoData) addMessages(pkg *packages.Package, e ProtoEntity) {
+	d.Messages = append(d.Messages, newProtoMessage(e.Singular, e.Fields...))
+
+	setterFields := append(append([]ProtoField(nil), e.SetterFields...), ProtoField{
+		Name: "mask", GoName: "Mask", Type: "google.protobuf.FieldMask",
+	})
+	d.Messages = append(d.Messages, newProtoMessage(e.Singular+"Setter", setterFields...))
+
+	if e.Searchable {
+		var searchFields []ProtoField
+		for _, f := range e.SearchFields {
+			searchFields = append(searchFields, f)
+			if f.Nullable {
+				searchFields = append(searchFields, ProtoField{
+					Name: f.Name + "_null", GoName: f.GoName + "Null", Type: "bool",
+				})
+			}
+		}
+		d.Messages = append(d.Messages, newProtoMessage(e.SearchType, searchFields...))
+	}
+
+	if len(e.LoadFields) > 0 {
+		d.Messages = append(d.Messages, newProtoMessage(e.Singular+"Load", e.LoadFields...))
+	}
+
+	var pkFields []ProtoField
+	for _, pk := range e.PKs {
+		if e.Key != nil {
+			pkFields = append(pkFields, ProtoField{Name: pk.Name, GoName: goCamelCase(pk.Name), Type: "string"})
+			continue
+		}
+
+		for _, f := range e.Fields {
+			if f.GoField == pk.Field {
+				f.Name, f.GoName = strcase.ToSnake(pk.Name), goCamelCase(strcase.ToSnake(pk.Name))
+				pkFields = append(pkFields, f)
+			}
+		}
+	}
+
+	obj := pkg.Types.Scope().Lookup(e.Singular)
+	s := pkgutil.ElemType(obj.Type()).(*types.Struct)
+	actor := func(typ string) []ProtoField {
+		if typ == "" || e.ContextActor {
+			return nil
+		}
+
+		for _, name := range []string{"CreatedBy", "UpdatedBy", "DeletedBy"} {
+			i := fieldIndex(s, name)
+			if i < 0 {
+				continue
+			}
+
+			t := s.Field(i).Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+
+			if pkgutil.NameInPackage(pkg, t) == typ {
+				if f, ok := newProtoField(pkg, "Actor", t); ok {
+					return []ProtoField{f}
+				}
+			}
+		}
+
+		return nil
+	}
+	version := func() []ProtoField {
+		if e.VersionType == "" {
+			return nil
+		}
+
+		f, _ := newProtoField(pkg, "Version", s.Field(fieldIndex(s, e.VersionField)).Type())
+		return []ProtoField{f}
+	}
+	join := func(fss ...[]ProtoField) []ProtoField {
+		var joined []ProtoField
+		for _, fs := range fss {
+			joined = append(joined, fs...)
+		}
+		return joined
+	}
+	message := func(name string, t string, label string) ProtoField {
+		return ProtoField{Name: name, Type: t, Label: label}
+	}
+	rpc := func(name, response string, fields ...ProtoField) {
+		d.Messages = append(d.Messages, newProtoMessage(name+"Request", fields...))
+		d.RPCs = append(d.RPCs, ProtoRPC{Name: name, Request: name + "Request", Response: response})
+	}
+	// rpcWithResponse adds an rpc returning a <name>Response message with
+	// the given response fields, or google.protobuf.Empty if there are
+	// none.
+	rpcWithResponse := func(name string, response []ProtoField, fields ...ProtoField) {
+		if len(response) == 0 {
+			rpc(name, "google.protobuf.Empty", fields...)
+			return
+		}
+
+		rpc(name, name+"Response", fields...)
+		d.Messages = append(d.Messages, newProtoMessage(name+"Response", response...))
+	}
+
+	upsertActor := e.CreatedByType
+	if upsertActor == "" {
+		upsertActor = e.UpdatedByType
+	}
+
+	if e.Create {
+		var res []ProtoField
+		if len(pkFields) == 1 {
+			res = pkFields
+		}
+		rpcWithResponse("Create"+e.Singular, res,
+			join(actor(e.CreatedByType), []ProtoField{message("data", e.Singular+"Setter", "")})...)
+	}
+
+	if e.CreateMany {
+		var res []ProtoField
+		if len(pkFields) == 1 {
+			pk := pkFields[0]
+			pk.Name, pk.GoName, pk.Label = pk.Name+"s", pk.GoName+"s", "repeated"
+			res = []ProtoField{pk}
+		}
+		rpcWithResponse("Create"+e.Plural, res,
+			join(actor(e.CreatedByType), []ProtoField{message("data", e.Singular+"Setter", "repeated")})...)
+	}
+
+	if e.Upsert {
+		rpc("Upsert"+e.Singular, "google.protobuf.Empty",
+			join(pkFields, actor(upsertActor), []ProtoField{message("data", e.Singular+"Setter", "")})...)
+	}
+
+	var load []ProtoField
+	if len(e.LoadFields) > 0 {
+		load = []ProtoField{message("load", e.Singular+"Load", "")}
+	}
+
+	if e.Get {
+		rpc("Get"+e.Singular, e.Singular, join(pkFields, load)...)
+	}
+
+	if e.Search && e.Searchable {
+		if e.Paginate != "" {
+			rpc("Search"+e.Plural, e.PageType, join([]ProtoField{message("search", e.SearchType, "")}, load)...)
+
+			pageFields := []ProtoField{message("items", e.Singular, "repeated"), {Name: "total", Type: "int64"}}
+			if e.Paginate == "cursor" {
+				pageFields = append(pageFields, ProtoField{Name: "next_cursor", Type: "string"})
+			}
+			d.Messages = append(d.Messages, newProtoMessage(e.PageType, pageFields...))
+		} else {
+			rpcWithResponse("Search"+e.Plural, []ProtoField{message("items", e.Singular, "repeated")},
+				join([]ProtoField{message("search", e.SearchType, "")}, load)...)
+		}
+	}
+
+	if e.Exists {
+		rpcWithResponse(e.Singular+"Exists", []ProtoField{{Name: "exists", Type: "bool"}}, pkFields...)
+	}
+
+	if e.Count && e.Searchable {
+		rpcWithResponse("Count"+e.Plural, []ProtoField{{Name: "count", Type: "int64"}},
+			message("search", e.SearchType, ""))
+	}
+
+	if e.Edit {
+		rpc("Edit"+e.Singular, "google.protobuf.Empty",
+			join(pkFields, version(), actor(e.UpdatedByType), []ProtoField{message("data", e.Singular+"Setter", "")})...)
+	}
+
+	if e.EditMany && e.Searchable {
+		rpcWithResponse("Edit"+e.Plural, []ProtoField{{Name: "edited", Type: "int64"}},
+			join([]ProtoField{message("search", e.SearchType, "")}, actor(e.UpdatedByType),
+				[]ProtoField{message("data", e.Singular+"Setter", "")})...)
+	}
+
+	if e.Delete {
+		rpc("Delete"+e.Singular, "google.protobuf.Empty", join(pkFields, version(), actor(e.DeletedByType))...)
+	}
+
+	if e.Restore {
+		rpc("Restore"+e.Singular, "google.protobuf.Empty", join(pkFields, actor(e.UpdatedByType))...)
+	}
+
+	if e.Purge {
+		rpc("Purge"+e.Singular, "google.protobuf.Empty", pkFields...)
+	}
+}
+
+// protoPackageName returns the proto package derived from the Go import path,
+// e.g. "example_com.app" for "example.com/app".
+func protoPackageName(importPath string) string {
+	segments := strings.Split(importPath, "/")
+	for i, s := range segments {
+		s = strings.Map(func(r rune) rune {
+			if r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+				return r
+			}
+			return '_'
+		}, s)
+		if s == "" || ('0' <= s[0] && s[0] <= '9') {
+			s = "_" + s
+		}
+
+		segments[i] = s
+	}
+
+	return strings.Join(segments, ".")
+}
+
+// newProtoMessage returns a message with the given fields, numbering them in
+// order.
+//
+// Fields without a type are listed as unsupported.
+func newProtoMessage(name string, fields ...ProtoField) ProtoMessage {
+	m := ProtoMessage{Name: name}
+
+	for _, f := range fields {
+		if f.Type == "" {
+			m.Unsupported = append(m.Unsupported, f)
+			continue
+		}
+
+		f.Num = len(m.Fields) + 1
+		m.Fields = append(m.Fields, f)
+	}
+
+	return m
+}
+
+// goCamelCase returns the name of the Go struct field generated by
+// protoc-gen-go for the proto field with the given name.
+func goCamelCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_' && i == 0:
+			b = append(b, 'X')
+		case c == '_' && i+1 < len(s) && isASCIILower(s[i+1]):
+			// skip over '_' in "_{{lowercase}}"
+		case isASCIIDigit(c):
+			b = append(b, c)
+		default:
+			if isASCIILower(c) {
+				c -= 'a' - 'A'
+			}
+			b = append(b, c)
+
+			for ; i+1 < len(s) && isASCIILower(s[i+1]); i++ {
+				b = append(b, s[i+1])
+			}
+		}
+	}
+
+	return string(b)
+}
+
+func isASCIILower(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// Supported reports whether f can be represented in proto.
+func (f ProtoField) Supported() bool {
+	return f.Type != ""
+}
+
+// ToProto returns the expression converting the Go value v to the value of
+// the field generated by protoc-gen-go.
+func (f ProtoField) ToProto(v string) string {
+	switch {
+	case f.Slice:
+		if f.GoType == f.PBGoType {
+			return v
+		}
+		return "protoSlice(" + v + ", func(v " + f.GoType + ") " + f.PBGoType + " { return " + f.to("v") + " })"
+	case f.Ptr && f.Type == timestampType:
+		return "protoTimestamp(" + v + ")"
+	case f.Ptr:
+		if f.GoType == f.PBGoType {
+			return v
+		}
+		return "protoPtr(" + v + ", func(v " + f.GoType + ") " + f.PBGoType + " { return " + f.to("v") + " })"
+	default:
+		return f.to(v)
+	}
+}
+
+// FromProto returns the expression converting the value v of the field
+// generated by protoc-gen-go to the Go value.
+func (f ProtoField) FromProto(v string) string {
+	switch {
+	case f.Slice:
+		if f.GoType == f.PBGoType {
+			return v
+		}
+		return "protoSlice(" + v + ", func(v " + f.PBGoType + ") " + f.GoType + " { return " + f.from("v") + " })"
+	case f.Ptr && f.Type == timestampType:
+		return "protoTime(" + v + ")"
+	case f.Ptr:
+		if f.GoType == f.PBGoType {
+			return v
+		}
+		return "protoPtr(" + v + ", func(v " + f.PBGoType + ") " + f.GoType + " { return " + f.from("v") + " })"
+	default:
+		return f.from(v)
+	}
+}
+
+func (f ProtoField) to(v string) string {
+	switch {
+	case f.Type == timestampType:
+		return "timestamppb.New(" + v + ")"
+	case f.GoType == f.PBGoType:
+		return v
+	default:
+		return f.PBGoType + "(" + v + ")"
+	}
+}
+
+func (f ProtoField) from(v string) string {
+	switch {
+	case f.Type == timestampType:
+		return v + ".AsTime()"
+	case f.GoType == f.PBGoType:
+		return v
+	default:
+		return f.GoType + "(" + v + ")"
+	}
+}
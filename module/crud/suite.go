@@ -0,0 +1,248 @@
+package crud
+
+import (
+	"github.com/mavolin/repogen/internal/goimports"
+	"github.com/mavolin/repogen/internal/pkgutil"
+	"github.com/mavolin/repogen/module/search"
+	"github.com/mavolin/repogen/module/setter"
+	"go/types"
+	"golang.org/x/tools/go/packages"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"text/template"
+)
+
+// suiteOutName is the name of the generated suite, which is generated into
+// the package <pkg>test, so that the package doesn't import testing.
+const suiteOutName = "crud_suite.repogen.go"
+
+// oldSuiteOutName is the name the suite was also generated under previously.
+// Before, the suite was generated into the package itself.
+const oldSuiteOutName = "crud_suite.repogen_test.go"
+
+var suiteTpl = template.Must(template.ParseFS(templates, "suite.gotpl"))
+
+var identRegexp = regexp.MustCompile(`[.\w]+`)
+
+type (
+	SuiteData struct {
+		// Package is the name of the generated package, i.e. <pkg>test.
+		Package string
+		// RepoPackage and RepoPath are the name and import path of the
+		// package the repositories are generated in.
+		RepoPackage, RepoPath string
+		Entities              []SuiteEntity
+		Errors                Errors
+	}
+
+	SuiteEntity struct {
+		Entity
+		qualifier
+
+		// SetterPKs are the names of the setter fields setting the pks, if
+		// the entity has multiple pks, all of which are settable.
+		SetterPKs []string
+		// KnownPKs indicates whether the pks of a created entity are known,
+		// i.e. whether the pk is returned by Create or SetterPKs is set.
+		KnownPKs bool
+		// DeletedField is the name of the search field filtering deleted
+		// entities, either IncludeDeleted or Deleted.
+		DeletedField string
+		// SearchChecks are the search fields whose results are checked by
+		// the suite.
+		SearchChecks []SuiteSearchCheck
+	}
+
+	// SuiteSearchCheck is a search field filtering a field of the entity
+	// by equality or range, whose results can be checked by comparing the
+	// field's values.
+	SuiteSearchCheck struct {
+		Name      string
+		FieldName string
+		// Op is the operator comparing the entity's field with the value
+		// searched for, e.g. "==" or ">=".
+		Op       string
+		Nullable bool
+	}
+
+	// qualifier qualifies the identifiers of the repository package.
+	qualifier struct {
+		// Qual is the qualifier of the repository package's identifiers,
+		// e.g. "app.".
+		Qual string
+		pkg  *types.Package
+		// generated are the names of the types generated into the
+		// repository package, which are not yet in its scope.
+		generated []string
+	}
+)
+
+// Q qualifies the identifiers declared by the repository package in the type
+// expression typ.
+func (q qualifier) Q(typ string) string {
+	return identRegexp.ReplaceAllStringFunc(typ, func(ident string) string {
+		if _, ok := q.pkg.Scope().Lookup(ident).(*types.TypeName); ok || slices.Contains(q.generated, ident) {
+			return q.Qual + ident
+		}
+
+		return ident
+	})
+}
+
+// generateSuite generates a test suite for all implementations of the
+// entities' repositories into the package <pkg>test.
+func generateSuite(pkg *packages.Package, es []Entity, errs Errors) error {
+	data := SuiteData{
+		Package:     pkg.Name + "test",
+		RepoPackage: pkg.Name,
+		RepoPath:    pkg.PkgPath,
+		Entities:    make([]SuiteEntity, len(es)),
+		Errors:      errs,
+	}
+
+	for i, e := range es {
+		var err error
+		data.Entities[i], err = suiteEntity(pkg, e)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(data.Package, 0o755); err != nil {
+		return wrapErr(err)
+	}
+
+	out, err := os.Create(filepath.Join(data.Package, suiteOutName))
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	in, done, err := goimports.Pipe(out)
+	if err != nil {
+		return wrapErr(err)
+	}
+
+	if err := suiteTpl.Execute(in, data); err != nil {
+		return wrapErr(err)
+	}
+
+	if err := in.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	if err = done(); err != nil {
+		_ = suiteTpl.Execute(out, data) // so the user can make sense of goimports err
+		return wrapErr(err)
+	}
+
+	if err := out.Close(); err != nil {
+		return wrapErr(err)
+	}
+
+	return nil
+}
+
+// removeOldSuite removes the suites previously generated into the package
+// itself.
+func removeOldSuite() {
+	_ = os.Remove(suiteOutName)
+	_ = os.Remove(oldSuiteOutName)
+}
+
+// removeSuite removes the generated suite of the package named pkgName, and
+// its package, if it is empty afterwards.
+func removeSuite(pkgName string) {
+	removeOldSuite()
+	_ = os.Remove(filepath.Join(pkgName+"test", suiteOutName))
+	_ = os.Remove(pkgName + "test")
+}
+
+func suiteEntity(pkg *packages.Package, e Entity) (SuiteEntity, error) {
+	obj := pkg.Types.Scope().Lookup(e.Singular)
+	s := pkgutil.ElemType(obj.Type()).(*types.Struct)
+
+	se := SuiteEntity{
+		Entity:    e,
+		qualifier: qualifier{Qual: pkg.Name + ".", pkg: pkg.Types},
+		KnownPKs:  len(e.PKs) == 1,
+	}
+	if e.Key != nil {
+		se.generated = []string{e.Key.Type}
+	}
+
+	if len(e.PKs) > 1 {
+		setterFields, err := setter.ListFields(pkg, obj, s)
+		if err != nil {
+			return se, err
+		}
+
+		se.SetterPKs = make([]string, 0, len(e.PKs))
+	PKs:
+		for _, pk := range e.PKs {
+			for _, sf := range setterFields {
+				if sf.FieldName == pk.Field && !sf.Converted {
+					se.SetterPKs = append(se.SetterPKs, sf.Name)
+					continue PKs
+				}
+			}
+
+			se.SetterPKs = nil
+			break
+		}
+
+		se.KnownPKs = se.SetterPKs != nil
+	}
+
+	searchEntity, err := search.FindEntity(pkg, obj)
+	if err != nil || searchEntity == nil {
+		return se, err
+	}
+
+	for _, f := range searchEntity.Fields {
+		if f.Name == "IncludeDeleted" || f.Name == "Deleted" {
+			se.DeletedField = f.Name
+		}
+
+		if check, ok := suiteSearchCheck(pkg, s, f); ok {
+			se.SearchChecks = append(se.SearchChecks, check)
+		}
+	}
+
+	return se, nil
+}
+
+// suiteSearchCheck returns the SuiteSearchCheck for the search field f of
+// the entity s, and whether its results can be checked.
+//
+// That is the case for equality and range fields, whose value is of the type
+// of the entity's field.
+func suiteSearchCheck(pkg *packages.Package, s *types.Struct, f search.Field) (SuiteSearchCheck, bool) {
+	check := SuiteSearchCheck{Name: f.Name, FieldName: f.FieldName, Nullable: f.IsNullable()}
+	switch f.Op {
+	case "eq":
+		check.Op = "=="
+	case "from":
+		check.Op = ">="
+	case "until":
+		check.Op = "<="
+	default:
+		return check, false
+	}
+
+	ef := pkgutil.LookupField(s, f.FieldName)
+	if ef == nil {
+		return check, false
+	}
+
+	typ := pkgutil.NameInPackage(pkg, ef.Type())
+	switch {
+	case check.Nullable && check.Op == "==":
+		return check, typ == "*"+f.ValType()
+	case check.Nullable:
+		return check, false
+	default:
+		return check, typ == f.ValType()
+	}
+}
@@ -0,0 +1,53 @@
+package cursorutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Encode returns the cursor of the item whose sort fields and pks have the
+// given values.
+//
+// A cursor is the position of an item in the results of a search, i.e. the
+// values of the item's sort fields in the order of the search's Sort,
+// followed by the values of its pks.
+// Cursors are opaque to clients, but are the same for all repository
+// implementations, so that they can be used interchangeably.
+func Encode(values ...any) (string, error) {
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("cursorutil: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Decode decodes cursor into dsts, which must be pointers to the sort fields
+// and pks of an item, in the same order as the values passed to Encode.
+//
+// It returns an error if cursor is malformed, or does not contain exactly
+// len(dsts) values.
+func Decode(cursor string, dsts ...any) error {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return fmt.Errorf("cursorutil: invalid cursor: %w", err)
+	}
+
+	var values []json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("cursorutil: invalid cursor: %w", err)
+	}
+
+	if len(values) != len(dsts) {
+		return fmt.Errorf("cursorutil: invalid cursor: expected %d values, but got %d", len(dsts), len(values))
+	}
+
+	for i, v := range values {
+		if err := json.Unmarshal(v, dsts[i]); err != nil {
+			return fmt.Errorf("cursorutil: invalid cursor: %w", err)
+		}
+	}
+
+	return nil
+}
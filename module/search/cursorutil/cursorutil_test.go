@@ -0,0 +1,75 @@
+package cursorutil
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	type postID int64
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	title := "a"
+
+	cursor, err := Encode(createdAt, &title, (*string)(nil), postID(42))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var (
+		actualCreatedAt time.Time
+		actualTitle     *string
+		actualNull      *string
+		actualID        postID
+	)
+	if err := Decode(cursor, &actualCreatedAt, &actualTitle, &actualNull, &actualID); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !actualCreatedAt.Equal(createdAt) {
+		t.Errorf("created at is %s, expected %s", actualCreatedAt, createdAt)
+	}
+	if actualTitle == nil || *actualTitle != title {
+		t.Errorf("title is %v, expected %q", actualTitle, title)
+	}
+	if actualNull != nil {
+		t.Errorf("null is %q, expected nil", *actualNull)
+	}
+	if actualID != 42 {
+		t.Errorf("id is %d, expected 42", actualID)
+	}
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	valid, err := Encode(1, "a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testCases := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "empty", cursor: ""},
+		{name: "not base64", cursor: "!"},
+		{name: "not json", cursor: base64.RawURLEncoding.EncodeToString([]byte("{"))},
+		{name: "not an array", cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"a":1}`))},
+		{name: "too few values", cursor: base64.RawURLEncoding.EncodeToString([]byte(`[1]`))},
+		{name: "too many values", cursor: base64.RawURLEncoding.EncodeToString([]byte(`[1,"a",2]`))},
+		{name: "wrong type", cursor: base64.RawURLEncoding.EncodeToString([]byte(`["a",1]`))},
+		{name: "padded", cursor: valid + "="},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var (
+				i int
+				s string
+			)
+			if err := Decode(c.cursor, &i, &s); err == nil {
+				t.Errorf("Decode(%q) returned no error", c.cursor)
+			}
+		})
+	}
+}
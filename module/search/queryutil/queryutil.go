@@ -0,0 +1,133 @@
+package queryutil
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/aarondl/opt/omit"
+	"github.com/aarondl/opt/omitnull"
+	"net/url"
+	"strconv"
+	"unsafe"
+)
+
+// ParamError is the error returned if a query parameter could not be parsed.
+type ParamError struct {
+	Param string
+	Err   error
+}
+
+func (e *ParamError) Error() string {
+	return fmt.Sprintf("invalid query parameter %q: %s", e.Param, e.Err.Error())
+}
+
+func (e *ParamError) Unwrap() error {
+	return e.Err
+}
+
+func ParseValue[T any](q url.Values, key string, parse func(string) (T, error), dst *T) error {
+	if !q.Has(key) {
+		return nil
+	}
+
+	t, err := parse(q.Get(key))
+	if err != nil {
+		return &ParamError{Param: key, Err: err}
+	}
+
+	*dst = t
+	return nil
+}
+
+func Parse[T any](q url.Values, key string, parse func(string) (T, error), dst *omit.Val[T]) error {
+	if !q.Has(key) {
+		return nil
+	}
+
+	t, err := parse(q.Get(key))
+	if err != nil {
+		return &ParamError{Param: key, Err: err}
+	}
+
+	dst.Set(t)
+	return nil
+}
+
+// ParseNull is the same as Parse, but sets dst to null, if the parameter is
+// present but empty.
+func ParseNull[T any](q url.Values, key string, parse func(string) (T, error), dst *omitnull.Val[T]) error {
+	if !q.Has(key) {
+		return nil
+	}
+
+	v := q.Get(key)
+	if v == "" {
+		dst.Null()
+		return nil
+	}
+
+	t, err := parse(v)
+	if err != nil {
+		return &ParamError{Param: key, Err: err}
+	}
+
+	dst.Set(t)
+	return nil
+}
+
+// ParseSlice parses all values of the repeated parameter key.
+func ParseSlice[T any, S ~[]T](q url.Values, key string, parse func(string) (T, error), dst *omit.Val[S]) error {
+	vs, ok := q[key]
+	if !ok {
+		return nil
+	}
+
+	s := make(S, len(vs))
+	for i, v := range vs {
+		t, err := parse(v)
+		if err != nil {
+			return &ParamError{Param: key, Err: err}
+		}
+
+		s[i] = t
+	}
+
+	dst.Set(s)
+	return nil
+}
+
+func ParseString[T ~string](s string) (T, error) {
+	return T(s), nil
+}
+
+func ParseBool[T ~bool](s string) (T, error) {
+	b, err := strconv.ParseBool(s)
+	return T(b), err
+}
+
+func ParseInt[T ~int | ~int8 | ~int16 | ~int32 | ~int64](s string) (T, error) {
+	var z T
+	i, err := strconv.ParseInt(s, 10, int(unsafe.Sizeof(z))*8)
+	return T(i), err
+}
+
+func ParseUint[T ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64](s string) (T, error) {
+	var z T
+	i, err := strconv.ParseUint(s, 10, int(unsafe.Sizeof(z))*8)
+	return T(i), err
+}
+
+func ParseFloat[T ~float32 | ~float64](s string) (T, error) {
+	var z T
+	f, err := strconv.ParseFloat(s, int(unsafe.Sizeof(z))*8)
+	return T(f), err
+}
+
+// ParseText parses s using T's UnmarshalText method.
+func ParseText[T any, PT interface {
+	*T
+	encoding.TextUnmarshaler
+}](s string) (T, error) {
+	var t T
+	err := PT(&t).UnmarshalText([]byte(s))
+	return t, err
+}
@@ -0,0 +1,100 @@
+package queryutil
+
+import (
+	"errors"
+	"github.com/aarondl/opt/omit"
+	"github.com/aarondl/opt/omitnull"
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestParseSlice(t *testing.T) {
+	testCases := []struct {
+		name      string
+		q         url.Values
+		expectSet bool
+		expect    []int
+		expectErr bool
+	}{
+		{name: "missing", q: url.Values{}},
+		{name: "other param", q: url.Values{"b": {"1"}}},
+		{name: "empty", q: url.Values{"a": {}}, expectSet: true, expect: []int{}},
+		{name: "single", q: url.Values{"a": {"1"}}, expectSet: true, expect: []int{1}},
+		{name: "repeated", q: url.Values{"a": {"1", "2"}}, expectSet: true, expect: []int{1, 2}},
+		{name: "invalid", q: url.Values{"a": {"1", "b"}}, expectErr: true},
+		{name: "empty value", q: url.Values{"a": {""}}, expectErr: true},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var dst omit.Val[[]int]
+
+			err := ParseSlice(c.q, "a", ParseInt[int], &dst)
+			if c.expectErr {
+				var perr *ParamError
+				if !errors.As(err, &perr) || perr.Param != "a" {
+					t.Fatalf("error is %v, expected a *ParamError for a", err)
+				}
+				if dst.IsSet() {
+					t.Errorf("dst is set to %v, expected it to be unset", dst.MustGet())
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			actual, ok := dst.Get()
+			if ok != c.expectSet {
+				t.Fatalf("dst is set: %t, expected %t", ok, c.expectSet)
+			}
+			if !slices.Equal(actual, c.expect) {
+				t.Errorf("dst is %v, expected %v", actual, c.expect)
+			}
+		})
+	}
+}
+
+func TestParseNull(t *testing.T) {
+	testCases := []struct {
+		name       string
+		q          url.Values
+		expectNull bool
+		expectSet  bool
+		expect     string
+	}{
+		{name: "missing", q: url.Values{}},
+		{name: "empty", q: url.Values{"a": {""}}, expectNull: true},
+		{name: "value", q: url.Values{"a": {"b"}}, expectSet: true, expect: "b"},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var dst omitnull.Val[string]
+			if err := ParseNull(c.q, "a", ParseString[string], &dst); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if dst.IsNull() != c.expectNull {
+				t.Errorf("dst is null: %t, expected %t", dst.IsNull(), c.expectNull)
+			}
+
+			actual, ok := dst.Get()
+			if ok != c.expectSet || actual != c.expect {
+				t.Errorf("dst is %q (set: %t), expected %q (set: %t)", actual, ok, c.expect, c.expectSet)
+			}
+		})
+	}
+}
+
+func TestParseInt(t *testing.T) {
+	if i, err := ParseInt[int8]("127"); err != nil || i != 127 {
+		t.Errorf(`ParseInt[int8]("127") = %d, %v, expected 127, nil`, i, err)
+	}
+	if _, err := ParseInt[int8]("128"); err == nil {
+		t.Error(`ParseInt[int8]("128") returned no error`)
+	}
+	if _, err := ParseUint[uint16]("-1"); err == nil {
+		t.Error(`ParseUint[uint16]("-1") returned no error`)
+	}
+}
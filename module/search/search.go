@@ -3,6 +3,7 @@ package search
 import (
 	"embed"
 	"fmt"
+	"github.com/iancoleman/strcase"
 	"github.com/mavolin/repogen/internal/goimports"
 	"github.com/mavolin/repogen/internal/pkgutil"
 	"github.com/mavolin/repogen/internal/util"
@@ -24,18 +25,113 @@ type (
 	Data struct {
 		Package  string
 		Entities []Entity
+		// DeletedFilter indicates whether the DeletedFilter type is used by
+		// any entity.
+		DeletedFilter bool
 	}
 
 	Entity struct {
 		SearchType string
+		ParseFunc  string
 		Fields     []Field
+
+		SortType      string
+		SortFieldType string
+		SortParseFunc string
+		SortFields    []SortField
+
+		// Paginate is the kind of pagination used by the search, i.e.
+		// "offset", "cursor" or empty if the search is not paginated.
+		Paginate string
+
+		// FilterType is the name of the filter type, or empty if no filter
+		// should be generated.
+		FilterType   string
+		FilterFields []Field
 	}
 	Field struct {
 		Name string
 		Type string
+
+		// FieldName is the name of the entity field this search field
+		// filters, if any.
+		FieldName string
+		// Op is the operator used to filter FieldName, e.g. "eq", "from",
+		// "until" or "in".
+		Op string
+		// FieldNames are the names of the entity fields searched by a
+		// fulltext field.
+		FieldNames []string
+
+		QueryKey string
+		// QueryFunc is the queryutil function used to parse this field from
+		// a query, or empty if the field cannot be parsed.
+		QueryFunc   string
+		QueryParser string
+	}
+	SortField struct {
+		Name string
+		Key  string
 	}
 )
 
+// IsNullable reports whether the field is of type omitnull.Val.
+func (f Field) IsNullable() bool {
+	return strings.HasPrefix(f.Type, "omitnull.Val[")
+}
+
+// ValType returns the type wrapped by the field's omit.Val or omitnull.Val.
+func (f Field) ValType() string {
+	typ := strings.TrimPrefix(f.Type, "omitnull.Val[")
+	typ = strings.TrimPrefix(typ, "omit.Val[")
+	if typ == f.Type {
+		return typ
+	}
+
+	return typ[:len(typ)-1]
+}
+
+// SQLFunc returns the name of the sqlutil function used to translate the
+// field to a where condition, or empty if the field is no filter.
+func (f Field) SQLFunc() string {
+	var name string
+	switch f.Op {
+	case "eq":
+		name = "Eq"
+	case "ne":
+		name = "Ne"
+	case "from":
+		name = "Gte"
+	case "until":
+		name = "Lte"
+	case "in":
+		return "In"
+	case "notin":
+		return "NotIn"
+	case "prefix":
+		return "Prefix"
+	case "contains":
+		return "Contains"
+	case "isnull":
+		return "IsNull"
+	case "overlaps":
+		return "Overlaps"
+	case "containsall":
+		return "ContainsAll"
+	case "fulltext":
+		return "FullText"
+	case "fulltextlike":
+		return "FullTextLike"
+	default:
+		return ""
+	}
+
+	if f.IsNullable() {
+		return name + "Null"
+	}
+	return name
+}
+
 func Generate(pkg *packages.Package) error {
 	es, err := findEntities(pkg)
 	if err != nil {
@@ -58,6 +154,13 @@ func Generate(pkg *packages.Package) error {
 		Package:  pkg.Name,
 		Entities: es,
 	}
+	for _, e := range es {
+		for _, f := range e.Fields {
+			if f.Type == "DeletedFilter" {
+				data.DeletedFilter = true
+			}
+		}
+	}
 
 	if err := tpl.Execute(in, data); err != nil {
 		return wrapErr(err)
@@ -84,58 +187,153 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 	es := make([]Entity, 0, len(scope.Names()))
 
 	for _, name := range scope.Names() {
-		obj := scope.Lookup(name)
-
-		dirs := pkgutil.FindDirectives(pkg, obj, "search")
-		if len(dirs) == 0 {
-			continue
+		e, err := FindEntity(pkg, scope.Lookup(name))
+		if err != nil {
+			return nil, err
+		} else if e != nil {
+			es = append(es, *e)
 		}
+	}
 
-		s, ok := pkgutil.ElemType(obj.Type()).(*types.Struct)
-		if !ok {
-			return nil, objErr(pkg, obj, "cannot generate interface for non-struct type")
-		}
+	return es, nil
+}
 
-		e := Entity{
-			SearchType: obj.Name() + "SearchData",
-		}
+// FindEntity returns the search entity generated for obj, or nil if obj has
+// no search directive.
+func FindEntity(pkg *packages.Package, obj types.Object) (*Entity, error) {
+	dirs := pkgutil.FindDirectives(pkg, obj, "search")
+	if len(dirs) == 0 {
+		return nil, nil
+	}
 
-		for _, dir := range dirs {
-			switch dir.Directive {
-			case "":
-				if dir.Args != "" {
-					e.SearchType = dir.Args
-				}
-			case "extra":
-				name, typ, _ := strings.Cut(dir.Args, " ")
-				e.Fields = append(e.Fields, Field{Name: name, Type: typ})
+	s, ok := pkgutil.ElemType(obj.Type()).(*types.Struct)
+	if !ok {
+		return nil, objErr(pkg, obj, "cannot generate interface for non-struct type")
+	}
+
+	e := Entity{
+		SearchType:    obj.Name() + "SearchData",
+		ParseFunc:     "Parse" + obj.Name() + "SearchData",
+		SortType:      obj.Name() + "Sort",
+		SortFieldType: obj.Name() + "SortField",
+		SortParseFunc: "Parse" + obj.Name() + "Sort",
+	}
+	var fullText []Field
+	var deletedFilter bool
+
+	for _, dir := range dirs {
+		switch dir.Directive {
+		case "":
+			if dir.Args != "" {
+				e.SearchType = dir.Args
+				e.ParseFunc = "Parse" + dir.Args
+			}
+		case "extra":
+			name, typ, _ := strings.Cut(dir.Args, " ")
+			e.Fields = append(e.Fields, Field{Name: name, Type: typ})
+		case "paginate":
+			switch dir.Args {
+			case "offset", "cursor":
+				e.Paginate = dir.Args
 			default:
-				return nil, objErr(pkg, obj, fmt.Sprintf("search: unrecognized directive %q", dir.Directive))
+				return nil, objErr(pkg, obj,
+					fmt.Sprintf(`invalid paginate directive %q, expected "paginate offset" or "paginate cursor"`, dir.Args))
+			}
+		case "fulltext", "fulltext:ilike":
+			name, fieldNames, _ := strings.Cut(dir.Args, " ")
+			if name == "" || fieldNames == "" {
+				return nil, objErr(pkg, obj,
+					fmt.Sprintf(`invalid fulltext directive %q, expected "fulltext <var> <fields...>"`, dir.Args))
+			}
+
+			for _, fieldName := range strings.Split(fieldNames, " ") {
+				f := pkgutil.LookupField(s, fieldName)
+				if f == nil {
+					return nil, objErr(pkg, obj, fmt.Sprintf("fulltext: no field named %q", fieldName))
+				} else if !isString(f.Type()) {
+					return nil, objErr(pkg, obj, fmt.Sprintf("%s: fulltext search requires a string field", fieldName))
+				}
+
+				fullText = addFullText(fullText, name, dir.Directive, fieldName)
 			}
+		case "deleted":
+			deletedFilter = true
+		case "filter":
+			e.FilterType = obj.Name() + "Filter"
+			if dir.Args != "" {
+				e.FilterType = dir.Args
+			}
+		default:
+			return nil, objErr(pkg, obj, fmt.Sprintf("search: unrecognized directive %q", dir.Directive))
 		}
+	}
 
-		fields, err := findSearchFields(pkg, obj, s)
-		if err != nil {
-			return nil, err
+	fields, fullText, err := findSearchFields(pkg, obj, s, fullText, deletedFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	fields = append(fields, fullText...)
+	e.Fields = append(e.Fields, fields...)
+
+	if e.FilterType != "" {
+		for _, f := range fields {
+			if f.Op != "" {
+				e.FilterFields = append(e.FilterFields, f)
+			}
 		}
 
-		e.Fields = append(e.Fields, fields...)
-		es = append(es, e)
+		e.Fields = append(e.Fields, Field{Name: "Filter", Type: "*" + e.FilterType})
 	}
 
-	return es, nil
+	e.SortFields = findSortFields(s)
+	if len(e.SortFields) > 0 {
+		e.Fields = append(e.Fields, Field{
+			Name:        "Sort",
+			Type:        "[]" + e.SortType,
+			QueryKey:    "sort",
+			QueryFunc:   "ParseValue",
+			QueryParser: e.SortParseFunc,
+		})
+	}
+
+	e.Fields = append(e.Fields, paginationFields(e.Paginate)...)
+	return &e, nil
 }
 
-func findSearchFields(pkg *packages.Package, obj types.Object, s *types.Struct) ([]Field, error) {
+func findSearchFields(
+	pkg *packages.Package, obj types.Object, s *types.Struct, fullText []Field, deletedFilter bool,
+) ([]Field, []Field, error) {
 	fields := make([]Field, 0, s.NumFields())
+	// the tenant is set by the repository, not the caller
+	tenant := util.TenantField(pkg, obj)
 
 	var includeDeleted bool
 
 	for i := 0; i < s.NumFields(); i++ {
 		f := s.Field(i)
+		if f.Name() == tenant {
+			continue
+		}
 		if f.Name() == "DeletedAt" || f.Name() == "DeletedBy" {
 			if !includeDeleted {
-				fields = append(fields, Field{Name: "IncludeDeleted", Type: "bool"})
+				if deletedFilter {
+					fields = append(fields, Field{
+						Name:        "Deleted",
+						Type:        "DeletedFilter",
+						QueryKey:    "deleted",
+						QueryFunc:   "ParseValue",
+						QueryParser: "ParseDeletedFilter",
+					})
+				} else {
+					fields = append(fields, Field{
+						Name:        "IncludeDeleted",
+						Type:        "bool",
+						QueryKey:    "includeDeleted",
+						QueryFunc:   "ParseValue",
+						QueryParser: "queryutil.ParseBool[bool]",
+					})
+				}
 				includeDeleted = true
 			}
 		}
@@ -152,41 +350,297 @@ func findSearchFields(pkg *packages.Package, obj types.Object, s *types.Struct)
 
 		settyp := util.Settyp(pkg, pkg, s.Tag(i), f.Type())
 		if settyp == nil {
-			return nil, objErr(pkg, obj, "cannot create setter for non-named type")
+			return nil, nil, objErr(pkg, obj, "cannot create setter for non-named type")
+		}
+
+		for _, spec := range strings.Split(search, ",") {
+			spec = strings.TrimSpace(spec)
+
+			op, name, _ := strings.Cut(spec, " ")
+			if op == "fulltext" || op == "fulltext:ilike" {
+				if !isString(f.Type()) {
+					return nil, nil, objErr(pkg, obj, fmt.Sprintf("%s: fulltext search requires a string field", f.Name()))
+				}
+
+				if name == "" {
+					name = "Query"
+				}
+				fullText = addFullText(fullText, name, op, f.Name())
+				continue
+			}
+
+			opFields, err := searchOpFields(pkg, obj, f, settyp, spec)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for i := range opFields {
+				setQuery(pkg, &opFields[i], f, tag)
+			}
+
+			fields = append(fields, opFields...)
+		}
+	}
+
+	return fields, fullText, nil
+}
+
+func searchOpFields(
+	pkg *packages.Package, obj types.Object, f *types.Var, settyp *util.SettypType, spec string,
+) ([]Field, error) {
+	field := func(suffix, op, typ string) Field {
+		return Field{Name: f.Name() + suffix, Type: typ, FieldName: f.Name(), Op: op}
+	}
+
+	split := strings.Split(spec, " ")
+	switch split[0] {
+	case "", "eq":
+		if len(split) == 2 {
+			return []Field{{Name: split[1], Type: settyp.OptionType(), FieldName: f.Name(), Op: "eq"}}, nil
+		}
+		return []Field{field("", "eq", settyp.OptionType())}, nil
+	case "range":
+		if len(split) != 3 && len(split) != 1 {
+			return nil, objErr(pkg, obj,
+				fmt.Sprintf(`invalid range directive %q, expected "range" or "range <fromVar> <untilVar"`, spec))
+		}
+
+		if len(split) == 1 {
+			return []Field{
+				field("From", "from", settyp.OptionType()),
+				field("Until", "until", settyp.OptionType()),
+			}, nil
+		}
+
+		return []Field{
+			{Name: split[1], Type: settyp.OptionType(), FieldName: f.Name(), Op: "from"},
+			{Name: split[2], Type: settyp.OptionType(), FieldName: f.Name(), Op: "until"},
+		}, nil
+	case "in", "notin", "prefix", "contains", "isnull", "ne", "overlaps", "containsall":
+		if len(split) > 2 {
+			return nil, objErr(pkg, obj,
+				fmt.Sprintf(`invalid %s directive %q, expected "%s" or "%s <var>"`, split[0], spec, split[0], split[0]))
 		}
 
-		split := strings.Split(search, " ")
+		var sf Field
 		switch split[0] {
-		case "":
-			fields = append(fields, Field{
-				Name: f.Name(),
-				Type: settyp.OptionType(),
-			})
-		case "range":
-			if len(split) != 3 && len(split) != 1 {
-				return nil, objErr(pkg, obj,
-					fmt.Sprintf(`invalid range directive %q, expected "range" or "range <fromVar> <untilVar"`, search))
+		case "in":
+			sf = field("In", "in", "omit.Val[[]"+settyp.Unptr()+"]")
+		case "notin":
+			sf = field("NotIn", "notin", "omit.Val[[]"+settyp.Unptr()+"]")
+		case "prefix", "contains":
+			if !isString(f.Type()) {
+				return nil, objErr(pkg, obj, fmt.Sprintf("%s: %s search requires a string field", f.Name(), split[0]))
 			}
 
-			if len(split) == 1 {
-				fields = append(fields,
-					Field{Name: f.Name() + "From", Type: settyp.OptionType()},
-					Field{Name: f.Name() + "Until", Type: settyp.OptionType()})
-				continue
+			if split[0] == "prefix" {
+				sf = field("Prefix", "prefix", "omit.Val["+settyp.Unptr()+"]")
+			} else {
+				sf = field("Contains", "contains", "omit.Val["+settyp.Unptr()+"]")
+			}
+		case "isnull":
+			if !settyp.IsPtr {
+				return nil, objErr(pkg, obj, fmt.Sprintf("%s: isnull search requires a nullable field", f.Name()))
+			}
+			sf = field("IsNull", "isnull", "omit.Val[bool]")
+		case "ne":
+			sf = field("Not", "ne", settyp.OptionType())
+		case "overlaps", "containsall":
+			if !isSlice(f.Type()) {
+				return nil, objErr(pkg, obj, fmt.Sprintf("%s: %s search requires a slice field", f.Name(), split[0]))
 			}
 
-			fields = append(fields,
-				Field{Name: split[1], Type: settyp.OptionType()},
-				Field{Name: split[2], Type: settyp.OptionType()})
-		default:
-			fields = append(fields, Field{
-				Name: search,
-				Type: settyp.OptionType(),
-			})
+			if split[0] == "overlaps" {
+				sf = field("Overlaps", "overlaps", "omit.Val["+settyp.Unptr()+"]")
+			} else {
+				sf = field("ContainsAll", "containsall", "omit.Val["+settyp.Unptr()+"]")
+			}
+		}
+
+		if len(split) == 2 {
+			sf.Name = split[1]
+		}
+		return []Field{sf}, nil
+	default:
+		return []Field{{Name: spec, Type: settyp.OptionType(), FieldName: f.Name(), Op: "eq"}}, nil
+	}
+}
+
+// addFullText adds fieldName to the fulltext field with the given name,
+// creating it if it doesn't exist yet.
+func addFullText(fullText []Field, name, op, fieldName string) []Field {
+	for i, f := range fullText {
+		if f.Name == name {
+			fullText[i].FieldNames = append(fullText[i].FieldNames, fieldName)
+			return fullText
+		}
+	}
+
+	if op == "fulltext:ilike" {
+		op = "fulltextlike"
+	}
+
+	return append(fullText, Field{
+		Name:        name,
+		Type:        "omit.Val[string]",
+		Op:          op,
+		FieldNames:  []string{fieldName},
+		QueryKey:    strcase.ToLowerCamel(name),
+		QueryFunc:   "Parse",
+		QueryParser: "queryutil.ParseString[string]",
+	})
+}
+
+func setQuery(pkg *packages.Package, sf *Field, f *types.Var, tag util.StructTag) {
+	sf.QueryKey = strcase.ToLowerCamel(sf.Name)
+	if strings.HasPrefix(sf.Name, f.Name()) {
+		key := tag["query"]
+		if key == "" {
+			key = strcase.ToLowerCamel(f.Name())
+		}
+		sf.QueryKey = key + sf.Name[len(f.Name()):]
+	}
+
+	// we can't know how to parse overridden types
+	if tag["settyp"] != "" || tag["rel"] != "" {
+		return
+	}
+
+	t := f.Type()
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	switch sf.Op {
+	case "isnull":
+		sf.QueryFunc = "Parse"
+		sf.QueryParser = "queryutil.ParseBool[bool]"
+		return
+	case "in", "notin":
+		sf.QueryFunc = "ParseSlice"
+	case "overlaps", "containsall":
+		sf.QueryFunc = "ParseSlice"
+		t = t.Underlying().(*types.Slice).Elem()
+	default:
+		if strings.HasPrefix(sf.Type, "omitnull.") {
+			sf.QueryFunc = "ParseNull"
+		} else {
+			sf.QueryFunc = "Parse"
+		}
+	}
+
+	sf.QueryParser = QueryParser(pkg, t)
+	if sf.QueryParser == "" {
+		sf.QueryFunc = ""
+	}
+}
+
+// QueryParser returns the function parsing a string to a value of type t, or
+// an empty string if t cannot be parsed.
+func QueryParser(pkg *packages.Package, t types.Type) string {
+	name := pkgutil.NameInPackage(pkg, t)
+	if name == "" {
+		return ""
+	}
+
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() == pkg.Types {
+		if dirs := pkgutil.FindDirectives(pkg, named.Obj(), "parseid"); len(dirs) > 0 {
+			if dirs[0].Args != "" {
+				return dirs[0].Args
+			}
+			return "Parse" + named.Obj().Name()
+		}
+	}
+
+	if m, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "UnmarshalText"); m != nil {
+		if _, ok := m.(*types.Func); ok {
+			return "queryutil.ParseText[" + name + "]"
+		}
+	}
+
+	basic, ok := t.Underlying().(*types.Basic)
+	if !ok {
+		return ""
+	}
+
+	info := basic.Info()
+	switch {
+	case info&types.IsString != 0:
+		return "queryutil.ParseString[" + name + "]"
+	case info&types.IsBoolean != 0:
+		return "queryutil.ParseBool[" + name + "]"
+	case info&types.IsUnsigned != 0:
+		return "queryutil.ParseUint[" + name + "]"
+	case info&types.IsInteger != 0:
+		return "queryutil.ParseInt[" + name + "]"
+	case info&types.IsFloat != 0:
+		return "queryutil.ParseFloat[" + name + "]"
+	default:
+		return ""
+	}
+}
+
+func isString(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	basic, ok := t.Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsString != 0
+}
+
+func isSlice(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+
+	_, ok := t.Underlying().(*types.Slice)
+	return ok
+}
+
+func findSortFields(s *types.Struct) []SortField {
+	var fields []SortField
+
+	for i := 0; i < s.NumFields(); i++ {
+		f := s.Field(i)
+
+		tag := util.ParseStructTag(s.Tag(i))
+		if tag == nil {
+			continue
+		}
+
+		key, ok := tag["sort"]
+		if !ok {
+			continue
+		}
+		if key == "" {
+			key = strcase.ToLowerCamel(f.Name())
 		}
+
+		fields = append(fields, SortField{Name: f.Name(), Key: key})
 	}
 
-	return fields, nil
+	return fields
+}
+
+func paginationFields(paginate string) []Field {
+	switch paginate {
+	case "offset":
+		return []Field{
+			{Name: "Limit", Type: "int", QueryKey: "limit", QueryFunc: "ParseValue", QueryParser: "queryutil.ParseInt[int]"},
+			{Name: "Offset", Type: "int", QueryKey: "offset", QueryFunc: "ParseValue", QueryParser: "queryutil.ParseInt[int]"},
+		}
+	case "cursor":
+		return []Field{
+			{Name: "Limit", Type: "int", QueryKey: "limit", QueryFunc: "ParseValue", QueryParser: "queryutil.ParseInt[int]"},
+			{Name: "After", Type: "string", QueryKey: "after", QueryFunc: "ParseValue",
+				QueryParser: "queryutil.ParseString[string]"},
+			{Name: "Before", Type: "string", QueryKey: "before", QueryFunc: "ParseValue",
+				QueryParser: "queryutil.ParseString[string]"},
+		}
+	default:
+		return nil
+	}
 }
 
 func wrapErr(err error) error {
@@ -0,0 +1,145 @@
+package sqlutil
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Order is a column search results are ordered by.
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// OrderBy returns the ORDER BY expression, without the keyword, ordering by
+// os.
+//
+// NULLs are ordered before all other values, as by the memory repositories.
+func OrderBy(os []Order) string {
+	var b strings.Builder
+	for i, o := range os {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(o.Column)
+		if o.Desc {
+			b.WriteString(" DESC NULLS LAST")
+		} else {
+			b.WriteString(" ASC NULLS FIRST")
+		}
+	}
+
+	return b.String()
+}
+
+// Reverse returns a copy of os with the order of each column reversed.
+func Reverse(os []Order) []Order {
+	os = slices.Clone(os)
+	for i := range os {
+		os[i].Desc = !os[i].Desc
+	}
+
+	return os
+}
+
+// Backwards reports whether a search paginated using cursors selects the
+// last limit results before the cursor before.
+//
+// If so, the results must be queried in reverse order, and are reversed
+// again by Page.
+func Backwards(after, before string, limit int) bool {
+	return before != "" && after == "" && limit > 0
+}
+
+// Limit returns the number of rows to query for a search paginated using
+// cursors.
+//
+// Unless the search is Backwards, this is one more than limit, so that Page
+// can tell if there is a next page.
+func Limit(after, before string, limit int) int {
+	if Backwards(after, before, limit) {
+		return limit
+	}
+
+	return limit + 1
+}
+
+// Page returns the results of a search paginated using cursors, given the
+// rows queried using Limit and, if the search is Backwards, the reverse
+// order, and whether there is a next page.
+func Page[T any](rows []T, after, before string, limit int) (page []T, more bool) {
+	switch {
+	case limit <= 0:
+		return rows, false
+	case Backwards(after, before, limit):
+		slices.Reverse(rows)
+		return rows, false
+	case len(rows) > limit:
+		return rows[:limit], true
+	default:
+		return rows, false
+	}
+}
+
+// After adds a condition matching the rows ordered after the row whose
+// ordered columns have the values vs, when ordered by os.
+func After(w *Where, os []Order, vs []any) {
+	var or Where
+	for i, o := range os {
+		var and Where
+		for j := 0; j < i; j++ {
+			and.Add(equal(os[j].Column, vs[j]))
+		}
+		and.Add(greater(o, vs[i]))
+
+		or.Add(and.And())
+	}
+
+	w.Add(or.Or())
+}
+
+// Before adds a condition matching the rows ordered before the row whose
+// ordered columns have the values vs, when ordered by os.
+func Before(w *Where, os []Order, vs []any) {
+	After(w, Reverse(os), vs)
+}
+
+// equal returns the condition matching rows where col equals v.
+func equal(col string, v any) (string, []any) {
+	if isNil(v) {
+		return col + " IS NULL", nil
+	}
+
+	return col + " = ?", []any{v}
+}
+
+// greater returns the condition matching rows where o.Column is ordered
+// after v, with NULLs ordered as by OrderBy.
+func greater(o Order, v any) (string, []any) {
+	switch {
+	case !o.Desc && isNil(v):
+		return o.Column + " IS NOT NULL", nil
+	case !o.Desc:
+		return o.Column + " > ?", []any{v}
+	case isNil(v):
+		return "FALSE", nil
+	default:
+		return "(" + o.Column + " < ? OR " + o.Column + " IS NULL)", []any{v}
+	}
+}
+
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
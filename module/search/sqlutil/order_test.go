@@ -0,0 +1,200 @@
+package sqlutil
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestOrderBy(t *testing.T) {
+	testCases := []struct {
+		name   string
+		os     []Order
+		expect string
+	}{
+		{name: "empty", expect: ""},
+		{name: "asc", os: []Order{{Column: "a"}}, expect: "a ASC NULLS FIRST"},
+		{name: "desc", os: []Order{{Column: "a", Desc: true}}, expect: "a DESC NULLS LAST"},
+		{
+			name:   "multiple",
+			os:     []Order{{Column: "a", Desc: true}, {Column: "b"}},
+			expect: "a DESC NULLS LAST, b ASC NULLS FIRST",
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := OrderBy(c.os); actual != c.expect {
+				t.Errorf("OrderBy(%v) = %q, expected %q", c.os, actual, c.expect)
+			}
+		})
+	}
+}
+
+func TestReverse(t *testing.T) {
+	os := []Order{{Column: "a"}, {Column: "b", Desc: true}}
+	expect := []Order{{Column: "a", Desc: true}, {Column: "b"}}
+
+	if actual := Reverse(os); !slices.Equal(actual, expect) {
+		t.Errorf("Reverse(%v) = %v, expected %v", os, actual, expect)
+	}
+	if os[0].Desc || !os[1].Desc {
+		t.Errorf("Reverse modified its input: %v", os)
+	}
+}
+
+func TestBackwardsLimit(t *testing.T) {
+	testCases := []struct {
+		name            string
+		after, before   string
+		limit           int
+		expectBackwards bool
+		expectLimit     int
+	}{
+		{name: "no cursors", limit: 10, expectLimit: 11},
+		{name: "after", after: "a", limit: 10, expectLimit: 11},
+		{name: "before", before: "b", limit: 10, expectBackwards: true, expectLimit: 10},
+		{name: "both", after: "a", before: "b", limit: 10, expectLimit: 11},
+		{name: "before no limit", before: "b", expectLimit: 1},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := Backwards(c.after, c.before, c.limit); actual != c.expectBackwards {
+				t.Errorf("Backwards(%q, %q, %d) = %t, expected %t",
+					c.after, c.before, c.limit, actual, c.expectBackwards)
+			}
+			if actual := Limit(c.after, c.before, c.limit); actual != c.expectLimit {
+				t.Errorf("Limit(%q, %q, %d) = %d, expected %d", c.after, c.before, c.limit, actual, c.expectLimit)
+			}
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	testCases := []struct {
+		name          string
+		rows          []int
+		after, before string
+		limit         int
+		expectPage    []int
+		expectMore    bool
+	}{
+		{name: "no limit", rows: []int{1, 2, 3}, expectPage: []int{1, 2, 3}},
+		{name: "more", rows: []int{1, 2, 3}, limit: 2, expectPage: []int{1, 2}, expectMore: true},
+		{name: "no more", rows: []int{1, 2}, limit: 2, expectPage: []int{1, 2}},
+		{name: "empty", rows: []int{}, limit: 2, expectPage: []int{}},
+		{name: "after more", rows: []int{3, 4, 5}, after: "a", limit: 2, expectPage: []int{3, 4}, expectMore: true},
+		{name: "backwards", rows: []int{3, 2}, before: "b", limit: 2, expectPage: []int{2, 3}},
+		{
+			name:       "both more",
+			rows:       []int{2, 3, 4},
+			after:      "a",
+			before:     "b",
+			limit:      2,
+			expectPage: []int{2, 3},
+			expectMore: true,
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			page, more := Page(c.rows, c.after, c.before, c.limit)
+			if !slices.Equal(page, c.expectPage) {
+				t.Errorf("page is %v, expected %v", page, c.expectPage)
+			}
+			if more != c.expectMore {
+				t.Errorf("more is %t, expected %t", more, c.expectMore)
+			}
+		})
+	}
+}
+
+func TestAfterBefore(t *testing.T) {
+	testCases := []struct {
+		name             string
+		os               []Order
+		vs               []any
+		expectAfter      string
+		expectAfterArgs  []any
+		expectBefore     string
+		expectBeforeArgs []any
+	}{
+		{
+			name:             "asc",
+			os:               []Order{{Column: "a"}},
+			vs:               []any{1},
+			expectAfter:      "a > ?",
+			expectAfterArgs:  []any{1},
+			expectBefore:     "(a < ? OR a IS NULL)",
+			expectBeforeArgs: []any{1},
+		},
+		{
+			name:         "asc null",
+			os:           []Order{{Column: "a"}},
+			vs:           []any{(*int)(nil)},
+			expectAfter:  "a IS NOT NULL",
+			expectBefore: "FALSE",
+		},
+		{
+			name:             "desc",
+			os:               []Order{{Column: "a", Desc: true}},
+			vs:               []any{1},
+			expectAfter:      "(a < ? OR a IS NULL)",
+			expectAfterArgs:  []any{1},
+			expectBefore:     "a > ?",
+			expectBeforeArgs: []any{1},
+		},
+		{
+			name:         "desc null",
+			os:           []Order{{Column: "a", Desc: true}},
+			vs:           []any{nil},
+			expectAfter:  "FALSE",
+			expectBefore: "a IS NOT NULL",
+		},
+		{
+			name:             "multiple",
+			os:               []Order{{Column: "a"}, {Column: "b", Desc: true}},
+			vs:               []any{1, 2},
+			expectAfter:      "(a > ?) OR ((a = ?) AND ((b < ? OR b IS NULL)))",
+			expectAfterArgs:  []any{1, 1, 2},
+			expectBefore:     "((a < ? OR a IS NULL)) OR ((a = ?) AND (b > ?))",
+			expectBeforeArgs: []any{1, 1, 2},
+		},
+		{
+			name:             "multiple null",
+			os:               []Order{{Column: "a"}, {Column: "b"}},
+			vs:               []any{nil, 2},
+			expectAfter:      "(a IS NOT NULL) OR ((a IS NULL) AND (b > ?))",
+			expectAfterArgs:  []any{2},
+			expectBefore:     "(FALSE) OR ((a IS NULL) AND ((b < ? OR b IS NULL)))",
+			expectBeforeArgs: []any{2},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var after Where
+			After(&after, c.os, c.vs)
+
+			cond, args := after.And()
+			if cond != c.expectAfter {
+				t.Errorf("After condition is %q, expected %q", cond, c.expectAfter)
+			}
+			if !reflect.DeepEqual(args, c.expectAfterArgs) {
+				t.Errorf("After args are %v, expected %v", args, c.expectAfterArgs)
+			}
+
+			var before Where
+			Before(&before, c.os, c.vs)
+
+			cond, args = before.And()
+			if cond != c.expectBefore {
+				t.Errorf("Before condition is %q, expected %q", cond, c.expectBefore)
+			}
+			if !reflect.DeepEqual(args, c.expectBeforeArgs) {
+				t.Errorf("Before args are %v, expected %v", args, c.expectBeforeArgs)
+			}
+		})
+	}
+}
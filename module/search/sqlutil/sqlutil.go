@@ -0,0 +1,199 @@
+package sqlutil
+
+import (
+	"github.com/aarondl/opt/omit"
+	"github.com/aarondl/opt/omitnull"
+	"strings"
+)
+
+// Where builds a where clause with ? placeholders from multiple conditions.
+type Where struct {
+	conds []string
+	args  []any
+}
+
+func (w *Where) Add(cond string, args []any) {
+	w.conds = append(w.conds, cond)
+	w.args = append(w.args, args...)
+}
+
+// And returns the conditions joined by AND, or TRUE if there are none.
+func (w *Where) And() (string, []any) {
+	return w.join(" AND ", "TRUE")
+}
+
+// Or returns the conditions joined by OR, or FALSE if there are none.
+func (w *Where) Or() (string, []any) {
+	return w.join(" OR ", "FALSE")
+}
+
+func (w *Where) join(sep, empty string) (string, []any) {
+	switch len(w.conds) {
+	case 0:
+		return empty, nil
+	case 1:
+		return w.conds[0], w.args
+	default:
+		return "(" + strings.Join(w.conds, ")"+sep+"(") + ")", w.args
+	}
+}
+
+func Not(cond string, args []any) (string, []any) {
+	return "NOT (" + cond + ")", args
+}
+
+func Eq[T any](w *Where, col string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" = ?", []any{t})
+	}
+}
+
+func EqNull[T any](w *Where, col string, v omitnull.Val[T]) {
+	if v.IsNull() {
+		w.Add(col+" IS NULL", nil)
+	} else if t, ok := v.Get(); ok {
+		w.Add(col+" = ?", []any{t})
+	}
+}
+
+func Ne[T any](w *Where, col string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" <> ?", []any{t})
+	}
+}
+
+func NeNull[T any](w *Where, col string, v omitnull.Val[T]) {
+	if v.IsNull() {
+		w.Add(col+" IS NOT NULL", nil)
+	} else if t, ok := v.Get(); ok {
+		w.Add("("+col+" IS NULL OR "+col+" <> ?)", []any{t})
+	}
+}
+
+func Gte[T any](w *Where, col string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" >= ?", []any{t})
+	}
+}
+
+// GteNull is the same as Gte, but ignores null values.
+func GteNull[T any](w *Where, col string, v omitnull.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" >= ?", []any{t})
+	}
+}
+
+func Lte[T any](w *Where, col string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" <= ?", []any{t})
+	}
+}
+
+// LteNull is the same as Lte, but ignores null values.
+func LteNull[T any](w *Where, col string, v omitnull.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" <= ?", []any{t})
+	}
+}
+
+func In[T any, S ~[]T](w *Where, col string, v omit.Val[S]) {
+	if s, ok := v.Get(); ok {
+		if len(s) == 0 {
+			w.Add("FALSE", nil)
+			return
+		}
+
+		w.Add(col+" IN ("+placeholders(len(s))+")", toArgs(s))
+	}
+}
+
+func NotIn[T any, S ~[]T](w *Where, col string, v omit.Val[S]) {
+	if s, ok := v.Get(); ok && len(s) > 0 {
+		w.Add(col+" NOT IN ("+placeholders(len(s))+")", toArgs(s))
+	}
+}
+
+func Prefix[T ~string](w *Where, col string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" LIKE ?", []any{EscapeLike(string(t)) + "%"})
+	}
+}
+
+// Contains adds a case-insensitive ILIKE condition.
+func Contains[T ~string](w *Where, col string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok {
+		w.Add(col+" ILIKE ?", []any{"%" + EscapeLike(string(t)) + "%"})
+	}
+}
+
+func IsNull(w *Where, col string, v omit.Val[bool]) {
+	if isNull, ok := v.Get(); ok {
+		if isNull {
+			w.Add(col+" IS NULL", nil)
+		} else {
+			w.Add(col+" IS NOT NULL", nil)
+		}
+	}
+}
+
+// Overlaps adds a condition matching array columns that contain at least
+// one of the values.
+func Overlaps[T any, S ~[]T](w *Where, col string, v omit.Val[S]) {
+	if s, ok := v.Get(); ok {
+		if len(s) == 0 {
+			w.Add("FALSE", nil)
+			return
+		}
+
+		w.Add("(? = ANY("+col+")"+strings.Repeat(" OR ? = ANY("+col+")", len(s)-1)+")", toArgs(s))
+	}
+}
+
+// ContainsAll adds a condition matching array columns that contain all the
+// values.
+func ContainsAll[T any, S ~[]T](w *Where, col string, v omit.Val[S]) {
+	if s, ok := v.Get(); ok && len(s) > 0 {
+		w.Add("(? = ANY("+col+")"+strings.Repeat(" AND ? = ANY("+col+")", len(s)-1)+")", toArgs(s))
+	}
+}
+
+// FullText adds a Postgres full-text search condition matching the
+// concatenation of cols against the websearch query v.
+func FullText[T ~string](w *Where, cols []string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok && t != "" {
+		w.Add("to_tsvector('simple', concat_ws(' ', "+strings.Join(cols, ", ")+")) @@ "+
+			"websearch_to_tsquery('simple', ?)", []any{string(t)})
+	}
+}
+
+// FullTextLike adds a condition matching rows where any of cols contains v
+// case-insensitively.
+func FullTextLike[T ~string](w *Where, cols []string, v omit.Val[T]) {
+	if t, ok := v.Get(); ok && t != "" {
+		like := "%" + EscapeLike(string(t)) + "%"
+
+		args := make([]any, len(cols))
+		for i := range args {
+			args[i] = like
+		}
+
+		w.Add("("+strings.Join(cols, " ILIKE ? OR ")+" ILIKE ?)", args)
+	}
+}
+
+// EscapeLike escapes the LIKE wildcards in s.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func placeholders(n int) string {
+	return strings.Repeat("?, ", n-1) + "?"
+}
+
+func toArgs[T any](s []T) []any {
+	args := make([]any, len(s))
+	for i, t := range s {
+		args[i] = t
+	}
+	return args
+}
@@ -0,0 +1,128 @@
+package sqlutil
+
+import (
+	"github.com/aarondl/opt/omit"
+	"reflect"
+	"testing"
+)
+
+func TestWhere(t *testing.T) {
+	testCases := []struct {
+		name                string
+		conds               [][]any
+		expectAnd, expectOr string
+		expectArgs          []any
+	}{
+		{name: "empty", expectAnd: "TRUE", expectOr: "FALSE"},
+		{
+			name:       "single",
+			conds:      [][]any{{"a = ?", 1}},
+			expectAnd:  "a = ?",
+			expectOr:   "a = ?",
+			expectArgs: []any{1},
+		},
+		{
+			name:       "multiple",
+			conds:      [][]any{{"a = ?", 1}, {"b IS NULL"}, {"c IN (?, ?)", 2, 3}},
+			expectAnd:  "(a = ?) AND (b IS NULL) AND (c IN (?, ?))",
+			expectOr:   "(a = ?) OR (b IS NULL) OR (c IN (?, ?))",
+			expectArgs: []any{1, 2, 3},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var w Where
+			for _, cond := range c.conds {
+				w.Add(cond[0].(string), cond[1:])
+			}
+
+			and, andArgs := w.And()
+			if and != c.expectAnd {
+				t.Errorf("And() = %q, expected %q", and, c.expectAnd)
+			}
+			if !reflect.DeepEqual(andArgs, c.expectArgs) {
+				t.Errorf("And() args are %v, expected %v", andArgs, c.expectArgs)
+			}
+
+			or, orArgs := w.Or()
+			if or != c.expectOr {
+				t.Errorf("Or() = %q, expected %q", or, c.expectOr)
+			}
+			if !reflect.DeepEqual(orArgs, c.expectArgs) {
+				t.Errorf("Or() args are %v, expected %v", orArgs, c.expectArgs)
+			}
+		})
+	}
+}
+
+func TestSetPredicates(t *testing.T) {
+	type predicate func(w *Where, col string, v omit.Val[[]int])
+
+	testCases := []struct {
+		name       string
+		predicate  predicate
+		v          omit.Val[[]int]
+		expect     string
+		expectArgs []any
+	}{
+		{name: "In unset", predicate: In[int, []int], expect: "TRUE"},
+		{name: "In empty", predicate: In[int, []int], v: omit.From([]int{}), expect: "FALSE"},
+		{
+			name:       "In",
+			predicate:  In[int, []int],
+			v:          omit.From([]int{1, 2}),
+			expect:     "col IN (?, ?)",
+			expectArgs: []any{1, 2},
+		},
+		{name: "NotIn unset", predicate: NotIn[int, []int], expect: "TRUE"},
+		{name: "NotIn empty", predicate: NotIn[int, []int], v: omit.From([]int{}), expect: "TRUE"},
+		{
+			name:       "NotIn",
+			predicate:  NotIn[int, []int],
+			v:          omit.From([]int{1}),
+			expect:     "col NOT IN (?)",
+			expectArgs: []any{1},
+		},
+		{name: "Overlaps unset", predicate: Overlaps[int, []int], expect: "TRUE"},
+		{name: "Overlaps empty", predicate: Overlaps[int, []int], v: omit.From([]int{}), expect: "FALSE"},
+		{
+			name:       "Overlaps",
+			predicate:  Overlaps[int, []int],
+			v:          omit.From([]int{1, 2}),
+			expect:     "(? = ANY(col) OR ? = ANY(col))",
+			expectArgs: []any{1, 2},
+		},
+		{name: "ContainsAll unset", predicate: ContainsAll[int, []int], expect: "TRUE"},
+		{name: "ContainsAll empty", predicate: ContainsAll[int, []int], v: omit.From([]int{}), expect: "TRUE"},
+		{
+			name:       "ContainsAll",
+			predicate:  ContainsAll[int, []int],
+			v:          omit.From([]int{1, 2}),
+			expect:     "(? = ANY(col) AND ? = ANY(col))",
+			expectArgs: []any{1, 2},
+		},
+	}
+
+	for _, c := range testCases {
+		t.Run(c.name, func(t *testing.T) {
+			var w Where
+			c.predicate(&w, "col", c.v)
+
+			cond, args := w.And()
+			if cond != c.expect {
+				t.Errorf("condition is %q, expected %q", cond, c.expect)
+			}
+			if !reflect.DeepEqual(args, c.expectArgs) {
+				t.Errorf("args are %v, expected %v", args, c.expectArgs)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	const s, expect = `50%_off\`, `50\%\_off\\`
+	if actual := EscapeLike(s); actual != expect {
+		t.Errorf("EscapeLike(%q) = %q, expected %q", s, actual, expect)
+	}
+}
@@ -33,6 +33,13 @@ type (
 	Field struct {
 		Name string
 		Type string
+
+		// FieldName is the name of the entity field set by this field, or
+		// empty if this is an extra field.
+		FieldName string
+		// Converted indicates whether the field's type differs from the
+		// entity field's type, e.g. because of a settyp or rel tag.
+		Converted bool
 	}
 )
 
@@ -116,7 +123,7 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 		}
 
 		var err error
-		e.Fields, err = listFields(pkg, obj, s)
+		e.Fields, err = ListFields(pkg, obj, s)
 		if err != nil {
 			return nil, err
 		}
@@ -128,8 +135,15 @@ func findEntities(pkg *packages.Package) ([]Entity, error) {
 	return es, nil
 }
 
-func listFields(pkg *packages.Package, obj types.Object, s *types.Struct) ([]Field, error) {
+// ListFields lists the setter fields of the entity obj with the underlying
+// struct s, excluding extra fields and the tenant field.
+func ListFields(pkg *packages.Package, obj types.Object, s *types.Struct) ([]Field, error) {
 	fields := make([]Field, 0, s.NumFields())
+	tenant := util.TenantField(pkg, obj)
+	version, err := util.VersionField(pkg, obj, s)
+	if err != nil {
+		return nil, objErr(pkg, obj, err.Error())
+	}
 
 	for i := 0; i < s.NumFields(); i++ {
 		f := s.Field(i)
@@ -137,7 +151,7 @@ func listFields(pkg *packages.Package, obj types.Object, s *types.Struct) ([]Fie
 		tag := util.ParseStructTag(s.Tag(i))
 
 		name := tag["set"]
-		if name == "-" {
+		if name == "-" || f.Name() == version || f.Name() == tenant {
 			continue
 		} else if name == "" {
 			switch f.Name() {
@@ -154,7 +168,12 @@ func listFields(pkg *packages.Package, obj types.Object, s *types.Struct) ([]Fie
 				fmt.Errorf("%s.%s: setter: cannot create setter for not-named type", obj.Name(), f.Name()))
 		}
 
-		fields = append(fields, Field{Name: name, Type: settyp.OptionType()})
+		fields = append(fields, Field{
+			Name:      name,
+			Type:      settyp.OptionType(),
+			FieldName: f.Name(),
+			Converted: tag["settyp"] != "" || tag["rel"] != "",
+		})
 	}
 
 	return fields, nil